@@ -0,0 +1,219 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package auth
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/TechCatsLab/redalert/protocol"
+)
+
+const (
+	// ChallengeSize size of challenge sent by server
+	ChallengeSize = 32
+	// MACSize size of client's answer to challenge
+	MACSize = sha256.Size
+	// MaxIDSize max size of client ID
+	MaxIDSize = 255
+)
+
+var (
+	// ErrRequired error for server requires authentication
+	ErrRequired = errors.New("Server requires authentication")
+	// ErrUnauthorized error for server rejects the client
+	ErrUnauthorized = errors.New("Authentication failed")
+	// ErrInvalidPacket error for auth packet malformed
+	ErrInvalidPacket = errors.New("Invalid auth packet")
+	// ErrNoKey error for key file has no key
+	ErrNoKey = errors.New("Key not found")
+)
+
+// Key is the ID of a client and the secret it shares with server
+type Key struct {
+	ID     string
+	Secret []byte
+}
+
+// Keyring holds secrets of clients by ID
+type Keyring map[string][]byte
+
+// readKeyFile parse file of lines "<id> <secret>", empty lines and lines
+// begin with # are ignored
+func readKeyFile(file string, fn func(id string, secret []byte) error) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) != 2 || len(fields[0]) > MaxIDSize {
+			return errors.New("Invalid key in " + file + " at line " + strconv.Itoa(line))
+		}
+
+		if err = fn(fields[0], []byte(fields[1])); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+// LoadKeyring read keyring from file
+func LoadKeyring(file string) (Keyring, error) {
+	keyring := make(Keyring)
+
+	err := readKeyFile(file, func(id string, secret []byte) error {
+		keyring[id] = secret
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return keyring, nil
+}
+
+// LoadKey read key of id from file, or the first one if id is empty
+func LoadKey(file, id string) (*Key, error) {
+	var key *Key
+
+	errFound := errors.New("found")
+	err := readKeyFile(file, func(keyID string, secret []byte) error {
+		if id == "" || id == keyID {
+			key = &Key{ID: keyID, Secret: secret}
+			return errFound
+		}
+
+		return nil
+	})
+	if err != nil && err != errFound {
+		return nil, err
+	}
+
+	if key == nil {
+		return nil, ErrNoKey
+	}
+
+	return key, nil
+}
+
+// NewChallenge generate a random challenge
+func NewChallenge() ([]byte, error) {
+	challenge := make([]byte, ChallengeSize)
+	if _, err := rand.Read(challenge); err != nil {
+		return nil, err
+	}
+
+	return challenge, nil
+}
+
+// Sign answer challenge for request, request is the first packet of client
+func Sign(secret, challenge, request []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(challenge)
+	mac.Write(request)
+
+	return mac.Sum(nil)
+}
+
+// Verify check answer of client id
+func (k Keyring) Verify(id string, challenge, request, mac []byte) bool {
+	secret, ok := k[id]
+	if !ok {
+		return false
+	}
+
+	return hmac.Equal(mac, Sign(secret, challenge, request))
+}
+
+// Match check secret of client id, it's for transports without challenge
+// such as HTTP basic auth over TLS
+func (k Keyring) Match(id string, secret []byte) bool {
+	expect, ok := k[id]
+	if !ok {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare(secret, expect) == 1
+}
+
+// Marshal build auth packet, it carries client ID and answer of challenge
+func Marshal(key *Key, challenge, request []byte) []byte {
+	packSize := 1 + len(key.ID) + MACSize
+
+	packet := protocol.Encode{
+		Body: make([]byte, protocol.FixedHeaderSize+packSize),
+	}
+	packet.Buffer = bytes.NewBuffer(packet.Body)
+	packet.Marshal(&protocol.Proto{
+		HeaderType: protocol.HeaderAuthType,
+		HeaderSize: protocol.FixedHeaderSize,
+		PackSize:   uint16(packSize),
+	})
+
+	body := packet.Body[protocol.FixedHeaderSize:]
+	body[0] = byte(len(key.ID))
+	copy(body[1:], key.ID)
+	copy(body[1+len(key.ID):], Sign(key.Secret, challenge, request))
+
+	return packet.Body
+}
+
+// Parse return client ID and answer in auth packet
+func Parse(packet []byte) (string, []byte, error) {
+	if len(packet) < protocol.FixedHeaderSize+1 || packet[0] != protocol.HeaderAuthType {
+		return "", nil, ErrInvalidPacket
+	}
+
+	body := packet[protocol.FixedHeaderSize:]
+	size := 1 + int(body[0]) + MACSize
+	if len(body) < size {
+		return "", nil, ErrInvalidPacket
+	}
+
+	return string(body[1 : 1+body[0]]), body[1+body[0] : size], nil
+}
@@ -0,0 +1,136 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package auth_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/TechCatsLab/redalert/auth"
+)
+
+var (
+	challenge = []byte("0123456789abcdef0123456789abcdef")
+	request   = []byte("request of client")
+	keyring   = auth.Keyring{"alice": []byte("a1"), "bob": []byte("b2")}
+)
+
+func TestVerify(t *testing.T) {
+	cases := []struct {
+		name    string
+		key     auth.Key
+		request []byte
+		ok      bool
+	}{
+		{"right key", auth.Key{ID: "alice", Secret: []byte("a1")}, request, true},
+		{"wrong secret", auth.Key{ID: "alice", Secret: []byte("b2")}, request, false},
+		{"unknown client", auth.Key{ID: "carol", Secret: []byte("a1")}, request, false},
+		{"other request", auth.Key{ID: "bob", Secret: []byte("b2")}, []byte("replayed"), false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			id, mac, err := auth.Parse(auth.Marshal(&c.key, challenge, c.request))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if id != c.key.ID {
+				t.Fatalf("got ID %q, want %q", id, c.key.ID)
+			}
+
+			if ok := keyring.Verify(id, challenge, request, mac); ok != c.ok {
+				t.Fatalf("verify is %v, want %v", ok, c.ok)
+			}
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	packet := auth.Marshal(&auth.Key{ID: "alice", Secret: []byte("a1")}, challenge, request)
+
+	for _, p := range [][]byte{nil, packet[:10], packet[:len(packet)-1], append([]byte{0x10}, packet[1:]...)} {
+		if _, _, err := auth.Parse(p); err != auth.ErrInvalidPacket {
+			t.Fatalf("parse %d bytes: got %v, want %v", len(p), err, auth.ErrInvalidPacket)
+		}
+	}
+}
+
+func TestLoadKey(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "keys")
+	data := "# clients\n\nalice a1\n  bob b2  \n"
+	if err := os.WriteFile(file, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	ring, err := auth.LoadKeyring(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ring) != 2 || string(ring["alice"]) != "a1" || string(ring["bob"]) != "b2" {
+		t.Fatalf("got keyring %v", ring)
+	}
+
+	cases := []struct {
+		id   string
+		want string
+		err  error
+	}{
+		{"", "alice", nil},
+		{"bob", "bob", nil},
+		{"carol", "", auth.ErrNoKey},
+	}
+
+	for _, c := range cases {
+		key, err := auth.LoadKey(file, c.id)
+		if err != c.err {
+			t.Fatalf("load %q: got %v, want %v", c.id, err, c.err)
+		}
+		if err == nil && key.ID != c.want {
+			t.Fatalf("load %q: got %q, want %q", c.id, key.ID, c.want)
+		}
+	}
+
+	if err = os.WriteFile(file, []byte("alice\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = auth.LoadKeyring(file); err == nil {
+		t.Fatal("key without secret is loaded")
+	}
+}
+
+func TestMatch(t *testing.T) {
+	if !keyring.Match("alice", []byte("a1")) {
+		t.Fatal("right secret doesn't match")
+	}
+	if keyring.Match("alice", []byte("b2")) || keyring.Match("carol", []byte("a1")) {
+		t.Fatal("wrong secret matches")
+	}
+}
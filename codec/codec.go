@@ -0,0 +1,200 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package codec
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+)
+
+// IDs of codecs shipped, ID is how a codec is negotiated in request options
+const (
+	Deflate byte = 1
+	Gzip    byte = 2
+)
+
+var (
+	// ErrTooLarge error for decompressed packet exceeds limit
+	ErrTooLarge = errors.New("Decompressed packet too large")
+	// ErrInvalidFlag error for flag of compressed packet unknown
+	ErrInvalidFlag = errors.New("Invalid compression flag")
+)
+
+// Codec compresses and decompresses streams
+type Codec interface {
+	Name() string
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+var (
+	mu     sync.RWMutex
+	codecs = make(map[byte]Codec)
+)
+
+func init() {
+	Register(Deflate, deflateCodec{})
+	Register(Gzip, gzipCodec{})
+}
+
+// Register make codec c available as id, id 0 is reserved for no compression
+func Register(id byte, c Codec) {
+	if id == 0 {
+		panic("codec: id 0 is reserved")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	codecs[id] = c
+}
+
+// Get return codec of id, nil if not registered
+func Get(id byte) Codec {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return codecs[id]
+}
+
+// Lookup return id of codec named name
+func Lookup(name string) (byte, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	for id, c := range codecs {
+		if strings.EqualFold(c.Name(), name) {
+			return id, true
+		}
+	}
+
+	return 0, false
+}
+
+type deflateCodec struct{}
+
+func (deflateCodec) Name() string {
+	return "deflate"
+}
+
+func (deflateCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return flate.NewWriter(w, flate.DefaultCompression)
+}
+
+func (deflateCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return flate.NewReader(r), nil
+}
+
+type gzipCodec struct{}
+
+func (gzipCodec) Name() string {
+	return "gzip"
+}
+
+func (gzipCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriter(w), nil
+}
+
+func (gzipCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+// Flags of a packet compressed by Pack
+const (
+	flagRaw        = 0
+	flagCompressed = 1
+)
+
+// Pack compress p as a packet, the first byte is a flag of whether the rest
+// is compressed, p is kept raw if it doesn't get smaller
+func Pack(c Codec, p []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	buf.WriteByte(flagCompressed)
+	w, err := c.NewWriter(&buf)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = w.Write(p); err != nil {
+		return nil, err
+	}
+
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+
+	if buf.Len() < len(p)+1 {
+		return buf.Bytes(), nil
+	}
+
+	return append([]byte{flagRaw}, p...), nil
+}
+
+// Unpack return content of packet p made by Pack, which is limit bytes at most
+func Unpack(c Codec, p []byte, limit int) ([]byte, error) {
+	if len(p) == 0 {
+		return nil, ErrInvalidFlag
+	}
+
+	switch p[0] {
+	case flagRaw:
+		if len(p)-1 > limit {
+			return nil, ErrTooLarge
+		}
+
+		return p[1:], nil
+
+	case flagCompressed:
+		r, err := c.NewReader(bytes.NewReader(p[1:]))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+
+		data, err := io.ReadAll(io.LimitReader(r, int64(limit)+1))
+		if err != nil {
+			return nil, err
+		}
+
+		if len(data) > limit {
+			return nil, ErrTooLarge
+		}
+
+		return data, nil
+	}
+
+	return nil, ErrInvalidFlag
+}
@@ -0,0 +1,138 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package codec_test
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/TechCatsLab/redalert/codec"
+)
+
+var (
+	text  = bytes.Repeat([]byte("redalert sends files "), 200)
+	noise = make([]byte, 4000)
+)
+
+func init() {
+	rand.New(rand.NewSource(1)).Read(noise)
+}
+
+func TestPack(t *testing.T) {
+	cases := []struct {
+		name  string
+		id    byte
+		data  []byte
+		limit int
+		err   error
+	}{
+		{"deflate text", codec.Deflate, text, len(text), nil},
+		{"gzip text", codec.Gzip, text, len(text), nil},
+		{"deflate noise", codec.Deflate, noise, len(noise), nil},
+		{"gzip empty", codec.Gzip, nil, 0, nil},
+		{"text over limit", codec.Deflate, text, len(text) - 1, codec.ErrTooLarge},
+		{"noise over limit", codec.Gzip, noise, len(noise) - 1, codec.ErrTooLarge},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cd := codec.Get(c.id)
+
+			packed, err := codec.Pack(cd, c.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(packed) > len(c.data)+1 {
+				t.Fatalf("packed %d bytes to %d", len(c.data), len(packed))
+			}
+
+			data, err := codec.Unpack(cd, packed, c.limit)
+			if err != c.err {
+				t.Fatalf("got %v, want %v", err, c.err)
+			}
+			if err == nil && !bytes.Equal(data, c.data) {
+				t.Fatal("unpacked data differs")
+			}
+		})
+	}
+}
+
+func TestUnpackInvalid(t *testing.T) {
+	cd := codec.Get(codec.Gzip)
+
+	for _, p := range [][]byte{nil, {9, 1, 2}} {
+		if _, err := codec.Unpack(cd, p, 100); err != codec.ErrInvalidFlag {
+			t.Fatalf("unpack %v: got %v, want %v", p, err, codec.ErrInvalidFlag)
+		}
+	}
+}
+
+func TestStream(t *testing.T) {
+	data := append(append([]byte(nil), text...), noise...)
+
+	for _, id := range []byte{codec.Deflate, codec.Gzip} {
+		cd := codec.Get(id)
+		t.Run(cd.Name(), func(t *testing.T) {
+			var out bytes.Buffer
+			w := codec.NewDecompressWriter(cd, &out)
+			if _, err := io.Copy(w, codec.NewCompressReader(cd, bytes.NewReader(data))); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(out.Bytes(), data) {
+				t.Fatal("decompressed stream differs")
+			}
+		})
+	}
+}
+
+func TestLookup(t *testing.T) {
+	cases := []struct {
+		name string
+		id   byte
+		ok   bool
+	}{
+		{"gzip", codec.Gzip, true},
+		{"DEFLATE", codec.Deflate, true},
+		{"zstd", 0, false},
+	}
+
+	for _, c := range cases {
+		id, ok := codec.Lookup(c.name)
+		if id != c.id || ok != c.ok {
+			t.Errorf("lookup %s: got %d %v, want %d %v", c.name, id, ok, c.id, c.ok)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package codec
+
+import (
+	"io"
+	"sync"
+)
+
+// NewCompressReader return a reader of the stream of r compressed by c
+func NewCompressReader(c Codec, r io.Reader) io.ReadCloser {
+	pr, pw := io.Pipe()
+
+	go func() {
+		w, err := c.NewWriter(pw)
+		if err == nil {
+			_, err = io.Copy(w, r)
+			if closeErr := w.Close(); err == nil {
+				err = closeErr
+			}
+		}
+
+		pw.CloseWithError(err)
+	}()
+
+	return pr
+}
+
+// decompressWriter write the stream decompressed by c to w
+type decompressWriter struct {
+	pw   *io.PipeWriter
+	done chan error
+	once sync.Once
+	err  error
+}
+
+// NewDecompressWriter return a writer, data written to it is a compressed
+// stream, the stream is decompressed by c and written to w. Close waits for
+// the end of stream and returns the error of decompressing or writing to w,
+// it may be called more than once.
+func NewDecompressWriter(c Codec, w io.Writer) io.WriteCloser {
+	pr, pw := io.Pipe()
+	d := &decompressWriter{
+		pw:   pw,
+		done: make(chan error, 1),
+	}
+
+	go func() {
+		r, err := c.NewReader(pr)
+		if err == nil {
+			_, err = io.Copy(w, r)
+			r.Close()
+		}
+
+		// data after the end of stream, or a failure, stops the writer
+		pr.CloseWithError(err)
+		d.done <- err
+	}()
+
+	return d
+}
+
+func (d *decompressWriter) Write(p []byte) (int, error) {
+	return d.pw.Write(p)
+}
+
+func (d *decompressWriter) Close() error {
+	d.once.Do(func() {
+		d.pw.Close()
+		d.err = <-d.done
+	})
+
+	return d.err
+}
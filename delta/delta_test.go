@@ -0,0 +1,157 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package delta_test
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/TechCatsLab/redalert/delta"
+)
+
+// random return n random bytes of seed
+func random(seed int64, n int) []byte {
+	b := make([]byte, n)
+	rand.New(rand.NewSource(seed)).Read(b)
+
+	return b
+}
+
+// concat return a copy of parts joined
+func concat(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
+
+func TestRoundTrip(t *testing.T) {
+	const block = delta.MinBlockSize
+
+	old := random(1, 20*block+100)
+	changed := append([]byte(nil), old...)
+	changed[10*block+7] ^= 0xff
+
+	cases := []struct {
+		name   string
+		old    []byte
+		new    []byte
+		reused int64 // min bytes copied from the old file
+	}{
+		{"same", old, old, int64(len(old))},
+		{"changed block", old, changed, int64(len(old) - block)},
+		// the short last block of old matches only at the end
+		{"appended", old, concat(old, random(2, 3000)), 20 * block},
+		{"prepended", old, concat(random(3, 77), old), int64(len(old))},
+		{"truncated", old, old[:5*block+3], 5 * block},
+		{"unrelated", old, random(4, 5000), 0},
+		{"empty old", nil, random(5, 5000), 0},
+		{"empty new", old, nil, 0},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			sig, err := delta.NewSignature(bytes.NewReader(c.old), block)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// signature is sent to the client and back as is
+			var wire bytes.Buffer
+			if _, err = sig.WriteTo(&wire); err != nil {
+				t.Fatal(err)
+			}
+			if sig, err = delta.ReadSignature(&wire); err != nil {
+				t.Fatal(err)
+			}
+
+			var out bytes.Buffer
+			p := delta.NewPatcher(sig, bytes.NewReader(c.old), &out)
+			if _, err = io.Copy(p, delta.NewReader(sig, bytes.NewReader(c.new))); err != nil {
+				t.Fatal(err)
+			}
+			if err = p.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(out.Bytes(), c.new) {
+				t.Fatal("patched file differs from new file")
+			}
+			if p.Reused() < c.reused {
+				t.Fatalf("reused %d bytes, want at least %d", p.Reused(), c.reused)
+			}
+		})
+	}
+}
+
+func TestPatchInvalid(t *testing.T) {
+	old := random(1, 4*delta.MinBlockSize)
+	sig, err := delta.NewSignature(bytes.NewReader(old), delta.MinBlockSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name   string
+		stream []byte
+	}{
+		{"unknown op", []byte{0x7f}},
+		{"short literal", []byte{0x01, 0, 0, 0, 9, 'a'}},
+		{"copy past end", []byte{0x02, 0, 0, 0, 3, 0, 0, 0, 2}},
+		{"empty copy", []byte{0x02, 0, 0, 0, 0, 0, 0, 0, 0}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p := delta.NewPatcher(sig, bytes.NewReader(old), io.Discard)
+			p.Write(c.stream)
+
+			if err := p.Close(); err != delta.ErrInvalidDelta {
+				t.Fatalf("got %v, want %v", err, delta.ErrInvalidDelta)
+			}
+		})
+	}
+}
+
+func TestReadSignatureInvalid(t *testing.T) {
+	sig, err := delta.NewSignature(bytes.NewReader(random(1, 3000)), delta.MinBlockSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wire bytes.Buffer
+	sig.WriteTo(&wire)
+	b := wire.Bytes()
+
+	// block count doesn't match size of file
+	b[7]++
+	if _, err = delta.ReadSignature(bytes.NewReader(b)); err != delta.ErrInvalidSignature {
+		t.Fatalf("got %v, want %v", err, delta.ErrInvalidSignature)
+	}
+}
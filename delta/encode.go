@@ -0,0 +1,257 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package delta
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"io"
+)
+
+// ops of delta stream
+const (
+	opLiteral = 0x01 // length(4) and data
+	opCopy    = 0x02 // index(4) and count(4) of blocks of the old file
+
+	maxLiteral = 1 << 16
+	readSize   = 1 << 16
+)
+
+// encoder write ops of delta stream, adjacent blocks are merged into one
+// copy
+type encoder struct {
+	w     *bufio.Writer
+	first uint32
+	count uint32
+}
+
+func (e *encoder) literal(p []byte) error {
+	if len(p) == 0 {
+		return nil
+	}
+
+	if err := e.flush(); err != nil {
+		return err
+	}
+
+	var header [5]byte
+	header[0] = opLiteral
+	binary.BigEndian.PutUint32(header[1:], uint32(len(p)))
+	e.w.Write(header[:])
+	_, err := e.w.Write(p)
+
+	return err
+}
+
+func (e *encoder) copy(index int) error {
+	if e.count > 0 && e.first+e.count == uint32(index) {
+		e.count++
+		return nil
+	}
+
+	if err := e.flush(); err != nil {
+		return err
+	}
+
+	e.first, e.count = uint32(index), 1
+
+	return nil
+}
+
+// flush write the pending copy
+func (e *encoder) flush() error {
+	if e.count == 0 {
+		return nil
+	}
+
+	var op [9]byte
+	op[0] = opCopy
+	binary.BigEndian.PutUint32(op[1:], e.first)
+	binary.BigEndian.PutUint32(op[5:], e.count)
+	e.count = 0
+
+	_, err := e.w.Write(op[:])
+
+	return err
+}
+
+// find return index of the block p matches, weak is the rolling checksum
+// of p
+func (s *Signature) find(index map[uint32][]int, weak uint32, p []byte) (int, bool) {
+	candidates, ok := index[weak]
+	if !ok {
+		return 0, false
+	}
+
+	strong := md5.Sum(p)
+	for _, i := range candidates {
+		if s.blockLen(i) == len(p) && bytes.Equal(s.Blocks[i].Strong[:], strong[:]) {
+			return i, true
+		}
+	}
+
+	return 0, false
+}
+
+// Encode write the delta of content of r against the file of s to w, the
+// delta is made of literal data and blocks of the file of s
+func (s *Signature) Encode(w io.Writer, r io.Reader) error {
+	index := make(map[uint32][]int, len(s.Blocks))
+	for i, block := range s.Blocks {
+		index[block.Weak] = append(index[block.Weak], i)
+	}
+
+	e := &encoder{w: bufio.NewWriter(w)}
+	bs := s.BlockSize
+	buf := make([]byte, 0, maxLiteral+bs+readSize)
+
+	// buf[lit:pos] is literal not written yet, buf[pos:pos+bs] is the window
+	var (
+		lit, pos int
+		a, b     uint32
+		rolling  bool
+		eof      bool
+	)
+
+	for {
+		for !eof && len(buf)-pos <= bs {
+			if lit > 0 {
+				buf = buf[:copy(buf, buf[lit:])]
+				pos -= lit
+				lit = 0
+			}
+
+			n, err := r.Read(buf[len(buf):cap(buf)])
+			buf = buf[:len(buf)+n]
+			if err == io.EOF {
+				eof = true
+			} else if err != nil {
+				return err
+			}
+		}
+
+		avail := len(buf) - pos
+		if avail < bs {
+			// only the last block can be shorter
+			window := buf[pos:]
+			i, ok := 0, false
+			if avail > 0 {
+				i, ok = s.find(index, weakSum(window), window)
+			}
+
+			if !ok {
+				if err := e.literal(buf[lit:]); err != nil {
+					return err
+				}
+				break
+			}
+
+			if err := e.literal(buf[lit:pos]); err != nil {
+				return err
+			}
+			if err := e.copy(i); err != nil {
+				return err
+			}
+
+			break
+		}
+
+		if !rolling {
+			a, b = sums(buf[pos : pos+bs])
+			rolling = true
+		}
+
+		if i, ok := s.find(index, a&0xffff|b<<16, buf[pos:pos+bs]); ok {
+			if err := e.literal(buf[lit:pos]); err != nil {
+				return err
+			}
+			if err := e.copy(i); err != nil {
+				return err
+			}
+
+			pos += bs
+			lit = pos
+			rolling = false
+			continue
+		}
+
+		if avail == bs {
+			// end of file, nothing to roll in
+			if err := e.literal(buf[lit:]); err != nil {
+				return err
+			}
+
+			break
+		}
+
+		out, in := uint32(buf[pos]), uint32(buf[pos+bs])
+		a = a - out + in
+		b = b - uint32(bs)*out + a
+		pos++
+
+		if pos-lit >= maxLiteral {
+			if err := e.literal(buf[lit:pos]); err != nil {
+				return err
+			}
+			lit = pos
+		}
+	}
+
+	if err := e.flush(); err != nil {
+		return err
+	}
+
+	return e.w.Flush()
+}
+
+// sums return the two parts of rolling checksum of p
+func sums(p []byte) (uint32, uint32) {
+	var a, b uint32
+	for i, c := range p {
+		a += uint32(c)
+		b += uint32(len(p)-i) * uint32(c)
+	}
+
+	return a, b
+}
+
+// NewReader return a reader of the delta of content of r against the file
+// of sig
+func NewReader(sig *Signature, r io.Reader) io.ReadCloser {
+	pr, pw := io.Pipe()
+
+	go func() {
+		pw.CloseWithError(sig.Encode(pw, r))
+	}()
+
+	return pr
+}
@@ -0,0 +1,155 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package delta
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"sync"
+)
+
+// Patcher rebuild a file from the delta written to it and the old file,
+// the file is written to w
+type Patcher struct {
+	sig    *Signature
+	base   io.ReaderAt
+	w      io.Writer
+	pw     *io.PipeWriter
+	done   chan error
+	once   sync.Once
+	err    error
+	reused int64
+}
+
+// NewPatcher return a Patcher, base is the old file of sig. Close waits for
+// the end of delta and returns the error of applying it or writing to w, it
+// may be called more than once.
+func NewPatcher(sig *Signature, base io.ReaderAt, w io.Writer) *Patcher {
+	pr, pw := io.Pipe()
+	p := &Patcher{
+		sig:  sig,
+		base: base,
+		w:    w,
+		pw:   pw,
+		done: make(chan error, 1),
+	}
+
+	go func() {
+		err := p.apply(bufio.NewReader(pr))
+
+		pr.CloseWithError(err)
+		p.done <- err
+	}()
+
+	return p
+}
+
+func (p *Patcher) apply(r *bufio.Reader) error {
+	var args [8]byte
+
+	for {
+		op, err := r.ReadByte()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch op {
+		case opLiteral:
+			if _, err = io.ReadFull(r, args[:4]); err != nil {
+				return ErrInvalidDelta
+			}
+
+			size := int64(binary.BigEndian.Uint32(args[:]))
+			if size > maxLiteral {
+				return ErrInvalidDelta
+			}
+
+			n, err := io.CopyN(p.w, r, size)
+			if err != nil {
+				if n < size && err == io.EOF {
+					return ErrInvalidDelta
+				}
+				return err
+			}
+
+		case opCopy:
+			if _, err = io.ReadFull(r, args[:]); err != nil {
+				return ErrInvalidDelta
+			}
+
+			first := int64(binary.BigEndian.Uint32(args[:]))
+			count := int64(binary.BigEndian.Uint32(args[4:]))
+			if count == 0 || first+count > int64(len(p.sig.Blocks)) {
+				return ErrInvalidDelta
+			}
+
+			offset := first * int64(p.sig.BlockSize)
+			size := count * int64(p.sig.BlockSize)
+			if offset+size > p.sig.Size {
+				size = p.sig.Size - offset
+			}
+
+			n, err := io.Copy(p.w, io.NewSectionReader(p.base, offset, size))
+			p.reused += n
+			if err != nil {
+				return err
+			}
+			if n != size {
+				return io.ErrUnexpectedEOF
+			}
+
+		default:
+			return ErrInvalidDelta
+		}
+	}
+}
+
+func (p *Patcher) Write(b []byte) (int, error) {
+	return p.pw.Write(b)
+}
+
+// Close finish the delta
+func (p *Patcher) Close() error {
+	p.once.Do(func() {
+		p.pw.Close()
+		p.err = <-p.done
+	})
+
+	return p.err
+}
+
+// Reused return bytes copied from the old file, it's valid after Close
+func (p *Patcher) Reused() int64 {
+	return p.reused
+}
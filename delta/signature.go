@@ -0,0 +1,187 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package delta
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+)
+
+const (
+	// MinBlockSize min size of block
+	MinBlockSize = 512
+	// MaxBlockSize max size of block
+	MaxBlockSize = 1 << 16
+
+	maxBlocks = 1 << 24
+
+	// signatureHeaderSize block size(4), block count(4) and size of file(8)
+	signatureHeaderSize = 16
+	blockSignatureSize  = 4 + md5.Size
+)
+
+var (
+	// ErrInvalidSignature error for signature can't be parsed
+	ErrInvalidSignature = errors.New("Invalid block signature")
+	// ErrInvalidDelta error for delta stream can't be applied
+	ErrInvalidDelta = errors.New("Invalid delta stream")
+)
+
+// Block signature of a block, Weak is the rolling checksum and Strong is
+// the MD5 of block
+type Block struct {
+	Weak   uint32
+	Strong [md5.Size]byte
+}
+
+// Signature signatures of blocks of a file, the last block may be shorter
+// than BlockSize
+type Signature struct {
+	BlockSize int
+	Size      int64
+	Blocks    []Block
+}
+
+// BlockSize return block size for a file of size, it's about the square
+// root of size
+func BlockSize(size int64) int {
+	n := int(math.Sqrt(float64(size))) &^ 7
+
+	if n < MinBlockSize {
+		return MinBlockSize
+	}
+
+	if n > MaxBlockSize {
+		return MaxBlockSize
+	}
+
+	return n
+}
+
+// NewSignature compute signature of content of r with blocks of blockSize
+func NewSignature(r io.Reader, blockSize int) (*Signature, error) {
+	if blockSize < MinBlockSize || blockSize > MaxBlockSize {
+		return nil, ErrInvalidSignature
+	}
+
+	sig := &Signature{BlockSize: blockSize}
+	buf := make([]byte, blockSize)
+
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			sig.Blocks = append(sig.Blocks, Block{
+				Weak:   weakSum(buf[:n]),
+				Strong: md5.Sum(buf[:n]),
+			})
+			sig.Size += int64(n)
+		}
+
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return sig, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// blockLen return length of block i
+func (s *Signature) blockLen(i int) int {
+	if i == len(s.Blocks)-1 {
+		return int(s.Size - int64(i)*int64(s.BlockSize))
+	}
+
+	return s.BlockSize
+}
+
+// WriteTo write s to w
+func (s *Signature) WriteTo(w io.Writer) (int64, error) {
+	buf := make([]byte, signatureHeaderSize+len(s.Blocks)*blockSignatureSize)
+
+	binary.BigEndian.PutUint32(buf, uint32(s.BlockSize))
+	binary.BigEndian.PutUint32(buf[4:], uint32(len(s.Blocks)))
+	binary.BigEndian.PutUint64(buf[8:], uint64(s.Size))
+
+	b := buf[signatureHeaderSize:]
+	for _, block := range s.Blocks {
+		binary.BigEndian.PutUint32(b, block.Weak)
+		copy(b[4:], block.Strong[:])
+		b = b[blockSignatureSize:]
+	}
+
+	n, err := w.Write(buf)
+
+	return int64(n), err
+}
+
+// ReadSignature read a signature written by WriteTo from r
+func ReadSignature(r io.Reader) (*Signature, error) {
+	header := make([]byte, signatureHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+
+	sig := &Signature{
+		BlockSize: int(binary.BigEndian.Uint32(header)),
+		Size:      int64(binary.BigEndian.Uint64(header[8:])),
+	}
+	count := int64(binary.BigEndian.Uint32(header[4:]))
+
+	if sig.BlockSize < MinBlockSize || sig.BlockSize > MaxBlockSize || count > maxBlocks ||
+		sig.Size < 0 || (sig.Size+int64(sig.BlockSize)-1)/int64(sig.BlockSize) != count {
+		return nil, ErrInvalidSignature
+	}
+
+	buf := make([]byte, count*blockSignatureSize)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+
+	sig.Blocks = make([]Block, count)
+	for i := range sig.Blocks {
+		sig.Blocks[i].Weak = binary.BigEndian.Uint32(buf)
+		copy(sig.Blocks[i].Strong[:], buf[4:blockSignatureSize])
+		buf = buf[blockSignatureSize:]
+	}
+
+	return sig, nil
+}
+
+// weakSum rolling checksum of p, as rsync does
+func weakSum(p []byte) uint32 {
+	a, b := sums(p)
+
+	return a&0xffff | b<<16
+}
@@ -0,0 +1,220 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package fec
+
+import (
+	"encoding/binary"
+
+	"github.com/TechCatsLab/redalert/protocol"
+)
+
+const (
+	// MinGroup min packs of a group
+	MinGroup = 2
+	// MaxGroup max packs of a group
+	MaxGroup = 64
+	// Overhead bytes a pack must leave to make the parity pack of its group
+	// fit in a pack, header and count of parity pack
+	Overhead = protocol.FixedHeaderSize + 1
+)
+
+// Encoder compute parity of a group of packs, parity is the XOR of packs,
+// a shorter pack is padded with zeros
+type Encoder struct {
+	parity []byte
+	count  int
+}
+
+// Add add pack p to group
+func (e *Encoder) Add(p []byte) {
+	if len(p) > len(e.parity) {
+		e.parity = append(e.parity, make([]byte, len(p)-len(e.parity))...)
+	}
+
+	xor(e.parity, p)
+	e.count++
+}
+
+// Count return packs of group
+func (e *Encoder) Count() int {
+	return e.count
+}
+
+// Marshal write parity pack of group to b and return its length, order is
+// the order of the first pack of group
+func (e *Encoder) Marshal(b []byte, order uint32) int {
+	b[protocol.HeaderTypeOffset] = protocol.HeaderParityType
+	binary.BigEndian.PutUint16(b[protocol.HeaderSizeOffset:], protocol.FixedHeaderSize)
+	binary.BigEndian.PutUint16(b[protocol.PackSizeOffset:], uint16(1+len(e.parity)))
+	binary.BigEndian.PutUint32(b[protocol.PackOrderOffset:], order)
+
+	b[protocol.FixedHeaderSize] = byte(e.count)
+
+	return protocol.FixedHeaderSize + 1 + copy(b[protocol.FixedHeaderSize+1:], e.parity)
+}
+
+// Reset start a new group
+func (e *Encoder) Reset() {
+	e.parity = e.parity[:0]
+	e.count = 0
+}
+
+// Decoder collect packs of a group in order, a group is complete once all
+// its packs, or all but one and the parity pack, are received
+type Decoder struct {
+	k      int
+	start  uint32
+	packs  [][]byte
+	parity []byte
+	count  int // packs of group, 0 until known
+	got    int
+}
+
+// NewDecoder create a Decoder of groups of k packs, the first group starts
+// at pack 1
+func NewDecoder(k int) *Decoder {
+	return &Decoder{
+		k:     k,
+		start: 1,
+		packs: make([][]byte, k),
+	}
+}
+
+// Start return order of the first pack of current group
+func (d *Decoder) Start() uint32 {
+	return d.start
+}
+
+// Add add pack p of order to group, it returns true if group is complete
+func (d *Decoder) Add(order uint32, p []byte) bool {
+	i := int(order - d.start)
+	if order < d.start || i >= d.k || (d.count > 0 && i >= d.count) {
+		return false
+	}
+
+	if d.packs[i] == nil {
+		d.packs[i] = append([]byte(nil), p...)
+		d.got++
+	}
+
+	return d.complete()
+}
+
+// AddParity add body of parity pack of order to group, it returns true if
+// group is complete
+func (d *Decoder) AddParity(order uint32, body []byte) bool {
+	if order != d.start || len(body) < 1 {
+		return false
+	}
+
+	count := int(body[0])
+	if count < 1 || count > d.k {
+		return false
+	}
+
+	d.count = count
+	d.parity = append([]byte(nil), body[1:]...)
+
+	return d.complete()
+}
+
+func (d *Decoder) complete() bool {
+	if d.got == d.k {
+		d.count = d.k
+	}
+
+	if d.count == 0 {
+		return false
+	}
+
+	return d.got == d.count || (d.got == d.count-1 && d.parity != nil)
+}
+
+// Packs return packs of the complete group in order, the lost pack is
+// recovered from parity, and start the next group. It returns nil if the
+// lost pack can't be recovered, then parity is dropped and the group waits
+// for the pack.
+func (d *Decoder) Packs() [][]byte {
+	packs := make([][]byte, 0, d.count)
+
+	for i := 0; i < d.count; i++ {
+		p := d.packs[i]
+		if p == nil {
+			if p = d.recover(i); p == nil {
+				d.parity = nil
+				return nil
+			}
+		}
+
+		packs = append(packs, p)
+	}
+
+	d.start += uint32(d.count)
+	for i := range d.packs {
+		d.packs[i] = nil
+	}
+	d.parity = nil
+	d.count = 0
+	d.got = 0
+
+	return packs
+}
+
+// recover recover pack i from parity and other packs
+func (d *Decoder) recover(i int) []byte {
+	p := append([]byte(nil), d.parity...)
+	for j := 0; j < d.count; j++ {
+		if j != i {
+			if len(d.packs[j]) > len(p) {
+				return nil
+			}
+			xor(p, d.packs[j])
+		}
+	}
+
+	if len(p) < protocol.FixedHeaderSize ||
+		binary.BigEndian.Uint32(p[protocol.PackOrderOffset:]) != d.start+uint32(i) {
+		return nil
+	}
+
+	size := protocol.FixedHeaderSize + int(binary.BigEndian.Uint16(p[protocol.PackSizeOffset:]))
+	if size > len(p) {
+		return nil
+	}
+
+	return p[:size]
+}
+
+// xor xor b into a, len(a) >= len(b)
+func xor(a, b []byte) {
+	for i := range b {
+		a[i] ^= b[i]
+	}
+}
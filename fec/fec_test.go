@@ -0,0 +1,150 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package fec_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/TechCatsLab/redalert/fec"
+	"github.com/TechCatsLab/redalert/protocol"
+)
+
+// pack return file pack of order with size bytes of body
+func pack(order uint32, size int) []byte {
+	p := make([]byte, protocol.FixedHeaderSize+size)
+	p[protocol.HeaderTypeOffset] = protocol.HeaderFileType
+	binary.BigEndian.PutUint16(p[protocol.PackSizeOffset:], uint16(size))
+	binary.BigEndian.PutUint32(p[protocol.PackOrderOffset:], order)
+	for i := protocol.FixedHeaderSize; i < len(p); i++ {
+		p[i] = byte(int(order)*31 + i)
+	}
+
+	return p
+}
+
+// group return packs of a group from order first, and body of its parity
+func group(first uint32, sizes []int) ([][]byte, []byte) {
+	var (
+		e     fec.Encoder
+		packs [][]byte
+	)
+	for i, size := range sizes {
+		p := pack(first+uint32(i), size)
+		packs = append(packs, p)
+		e.Add(p)
+	}
+
+	parity := make([]byte, protocol.FixedHeaderSize+1+protocol.FixedHeaderSize+1500)
+	n := e.Marshal(parity, first)
+
+	return packs, parity[protocol.FixedHeaderSize:n]
+}
+
+func TestRecover(t *testing.T) {
+	cases := []struct {
+		name   string
+		k      int
+		sizes  []int
+		lost   []int // index of packs lost
+		parity bool  // parity pack is received
+		ok     bool  // group is complete
+	}{
+		{"no loss", 4, []int{100, 100, 100, 100}, nil, false, true},
+		{"lost first", 4, []int{100, 100, 100, 100}, []int{0}, true, true},
+		{"lost last", 4, []int{100, 100, 100, 100}, []int{3}, true, true},
+		{"lost shorter", 4, []int{100, 100, 37, 100}, []int{2}, true, true},
+		{"short group", 4, []int{100, 60}, []int{1}, true, true},
+		{"lost two", 4, []int{100, 100, 100, 100}, []int{1, 2}, true, false},
+		{"lost without parity", 4, []int{100, 100, 100, 100}, []int{1}, false, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			packs, parity := group(1, c.sizes)
+			d := fec.NewDecoder(c.k)
+
+			lost := make(map[int]bool)
+			for _, i := range c.lost {
+				lost[i] = true
+			}
+
+			complete := false
+			for i, p := range packs {
+				if !lost[i] {
+					complete = d.Add(uint32(1+i), p)
+				}
+			}
+			if c.parity {
+				complete = d.AddParity(1, parity)
+			}
+
+			if complete != c.ok {
+				t.Fatalf("complete is %v, want %v", complete, c.ok)
+			}
+			if !complete {
+				return
+			}
+
+			got := d.Packs()
+			if len(got) != len(packs) {
+				t.Fatalf("got %d packs, want %d", len(got), len(packs))
+			}
+			for i := range packs {
+				if !bytes.Equal(got[i], packs[i]) {
+					t.Fatalf("pack %d differs", i)
+				}
+			}
+
+			if d.Start() != uint32(1+len(packs)) {
+				t.Fatalf("next group starts at %d, want %d", d.Start(), 1+len(packs))
+			}
+		})
+	}
+}
+
+func TestDecoderIgnore(t *testing.T) {
+	d := fec.NewDecoder(4)
+	packs, parity := group(1, []int{10, 10, 10, 10})
+
+	// packs of other groups and parity of another group are ignored
+	if d.Add(5, pack(5, 10)) || d.AddParity(5, parity) {
+		t.Fatal("pack of another group is added")
+	}
+
+	// a repeated pack doesn't count twice
+	for _, p := range packs[:3] {
+		d.Add(binary.BigEndian.Uint32(p[protocol.PackOrderOffset:]), p)
+	}
+	if d.Add(3, packs[2]) {
+		t.Fatal("group is complete without pack 4")
+	}
+}
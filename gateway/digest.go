@@ -0,0 +1,150 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package gateway
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"hash"
+	"io"
+	"strings"
+)
+
+// digestHeader is the header of RFC 9530 carrying digests of content
+const digestHeader = "Content-Digest"
+
+var (
+	// ErrDigest error for Content-Digest malformed or of no supported algorithm
+	ErrDigest = errors.New("Invalid or unsupported Content-Digest")
+	// ErrMismatch error for content not match its Content-Digest
+	ErrMismatch = errors.New("Content-Digest mismatch")
+)
+
+// algorithms are digests supported, by their names in Content-Digest
+var algorithms = map[string]func() hash.Hash{
+	"sha-256": sha256.New,
+	"sha-512": sha512.New,
+}
+
+// verifier hash content and check it against digests of a Content-Digest
+type verifier struct {
+	digests map[string][]byte
+	hashes  map[string]hash.Hash
+}
+
+// parseDigest parse Content-Digest such as "sha-256=:<base64>:", digests of
+// algorithms not supported are ignored, but one must be supported
+func parseDigest(header string) (map[string][]byte, error) {
+	digests := make(map[string][]byte)
+
+	for _, member := range strings.Split(header, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(member), "=")
+		if !ok {
+			return nil, ErrDigest
+		}
+
+		name = strings.ToLower(name)
+		if _, ok = algorithms[name]; !ok {
+			continue
+		}
+
+		value, _, _ = strings.Cut(value, ";")
+		if len(value) < 2 || value[0] != ':' || value[len(value)-1] != ':' {
+			return nil, ErrDigest
+		}
+
+		sum, err := base64.StdEncoding.DecodeString(value[1 : len(value)-1])
+		if err != nil {
+			return nil, ErrDigest
+		}
+		digests[name] = sum
+	}
+
+	if len(digests) == 0 {
+		return nil, ErrDigest
+	}
+
+	return digests, nil
+}
+
+// formatDigest format sum of algorithm name as Content-Digest
+func formatDigest(name string, sum []byte) string {
+	return name + "=:" + base64.StdEncoding.EncodeToString(sum) + ":"
+}
+
+// newVerifier create a verifier hashing content by every supported
+// algorithm, so the digest may come later in a trailer
+func newVerifier() *verifier {
+	v := &verifier{
+		hashes: make(map[string]hash.Hash),
+	}
+
+	for name, fn := range algorithms {
+		v.hashes[name] = fn()
+	}
+
+	return v
+}
+
+func (v *verifier) Write(p []byte) (int, error) {
+	for _, h := range v.hashes {
+		h.Write(p)
+	}
+
+	return len(p), nil
+}
+
+// writer return a writer to both w and v
+func (v *verifier) writer(w io.Writer) io.Writer {
+	return io.MultiWriter(w, v)
+}
+
+// verify check content against digests, every digest must match
+func (v *verifier) verify() error {
+	if len(v.digests) == 0 {
+		return ErrDigest
+	}
+
+	for name, sum := range v.digests {
+		if !bytes.Equal(v.hashes[name].Sum(nil), sum) {
+			return ErrMismatch
+		}
+	}
+
+	return nil
+}
+
+// digest return Content-Digest of content hashed
+func (v *verifier) digest() string {
+	return formatDigest("sha-256", v.hashes["sha-256"].Sum(nil))
+}
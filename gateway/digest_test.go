@@ -0,0 +1,135 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package gateway
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"testing"
+)
+
+var (
+	content = []byte("content of file")
+	sum256  = sha256.Sum256(content)
+	sum512  = sha512.Sum512(content)
+	b64     = base64.StdEncoding.EncodeToString(sum256[:])
+)
+
+func TestParseDigest(t *testing.T) {
+	cases := []struct {
+		name    string
+		header  string
+		digests []string // algorithms parsed
+		err     error
+	}{
+		{"sha-256", "sha-256=:" + b64 + ":", []string{"sha-256"}, nil},
+		{"upper case name", "SHA-256=:" + b64 + ":", []string{"sha-256"}, nil},
+		{"parameters", "sha-256=:" + b64 + ":;a=1", []string{"sha-256"}, nil},
+		{"two algorithms", "sha-512=:" + base64.StdEncoding.EncodeToString(sum512[:]) + ":, sha-256=:" + b64 + ":", []string{"sha-256", "sha-512"}, nil},
+		{"unsupported ignored", "md5=:AAAA:, sha-256=:" + b64 + ":", []string{"sha-256"}, nil},
+		{"only unsupported", "md5=:AAAA:", nil, ErrDigest},
+		{"empty", "", nil, ErrDigest},
+		{"no value", "sha-256", nil, ErrDigest},
+		{"no colons", "sha-256=" + b64, nil, ErrDigest},
+		{"one colon", "sha-256=:", nil, ErrDigest},
+		{"bad base64", "sha-256=:not base64!:", nil, ErrDigest},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			digests, err := parseDigest(c.header)
+			if err != c.err {
+				t.Fatalf("got %v, want %v", err, c.err)
+			}
+			if len(digests) != len(c.digests) {
+				t.Fatalf("got %d digests, want %d", len(digests), len(c.digests))
+			}
+			for _, name := range c.digests {
+				if _, ok := digests[name]; !ok {
+					t.Fatalf("%s not parsed", name)
+				}
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	cases := []struct {
+		name   string
+		header string
+		err    error
+	}{
+		{"match", "sha-256=:" + b64 + ":", nil},
+		{"both match", "sha-256=:" + b64 + ":, sha-512=:" + base64.StdEncoding.EncodeToString(sum512[:]) + ":", nil},
+		{"mismatch", "sha-256=:" + base64.StdEncoding.EncodeToString(sum512[:32]) + ":", ErrMismatch},
+		{"one mismatch", "sha-256=:" + b64 + ":, sha-512=:" + base64.StdEncoding.EncodeToString(sum256[:]) + ":", ErrMismatch},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			v := newVerifier()
+			digests, err := parseDigest(c.header)
+			if err != nil {
+				t.Fatal(err)
+			}
+			v.digests = digests
+
+			var out bytes.Buffer
+			v.writer(&out).Write(content)
+
+			if err = v.verify(); err != c.err {
+				t.Fatalf("got %v, want %v", err, c.err)
+			}
+			if !bytes.Equal(out.Bytes(), content) {
+				t.Fatal("content isn't written through")
+			}
+		})
+	}
+
+	// without a digest nothing is verified
+	if err := newVerifier().verify(); err != ErrDigest {
+		t.Fatalf("got %v, want %v", err, ErrDigest)
+	}
+}
+
+func TestFormatDigest(t *testing.T) {
+	v := newVerifier()
+	v.Write(content)
+
+	digests, err := parseDigest(v.digest())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(digests["sha-256"], sum256[:]) {
+		t.Fatal("formatted digest differs")
+	}
+}
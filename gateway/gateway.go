@@ -0,0 +1,432 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package gateway
+
+import (
+	"crypto/tls"
+	"errors"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TechCatsLab/redalert/auth"
+	"github.com/TechCatsLab/redalert/logger"
+	"github.com/TechCatsLab/redalert/metrics"
+	"github.com/TechCatsLab/redalert/policy"
+	"github.com/TechCatsLab/redalert/protocol"
+	"github.com/TechCatsLab/redalert/ratelimit"
+	"github.com/TechCatsLab/redalert/storage"
+)
+
+const (
+	readHeaderTimeout = 10 * time.Second
+
+	// network is the transport of gateway in metrics
+	network = "http"
+)
+
+var (
+	// ErrName error for request names no file
+	ErrName = errors.New("File name required")
+
+	errDownload = errors.New("Download not enabled")
+)
+
+// Conf gateway configure
+type Conf struct {
+	Addr          string          // Local address such as ":8081"
+	Storage       storage.Storage // Where uploaded files go, default to protocol.DefaultDir
+	Export        storage.Source  // Where downloaded files come from, download is disabled if nil
+	TLS           *tls.Config     // Serve HTTPS if not nil
+	Auth          auth.Keyring    // Clients must send ID and secret by basic auth if not nil
+	Policy        *policy.Policy  // What clients may do, everything if nil
+	Limit         float64         // Bytes per second of all transfers, no limit if 0
+	ClientLimit   float64         // Bytes per second of a client, by ID or source IP, no limit if 0
+	RequireDigest bool            // Reject uploads without Content-Digest in header or trailer
+	Listener      net.Listener    // Serve on it instead of listening on Addr if not nil
+	Log           logger.Logger   // Where messages go, default to logger.Default()
+}
+
+// Server is an HTTP gateway of storage, a file is uploaded by PUT or POST to
+// its path, or POST to a dir with file name in Content-Disposition, and
+// downloaded by GET from export.
+type Server struct {
+	conf     *Conf
+	storage  storage.Storage
+	limit    *ratelimit.Bucket
+	clients  *ratelimit.Group
+	listener net.Listener
+	http     *http.Server
+	log      logger.Logger
+}
+
+// objectWriter write to object sequentially, it stops at limit
+type objectWriter struct {
+	storage.Object
+	offset int64
+	limit  int64 // max size of file, no limit if 0
+}
+
+func (w *objectWriter) Write(p []byte) (int, error) {
+	if w.limit > 0 && w.offset+int64(len(p)) > w.limit {
+		return 0, policy.ErrTooLarge
+	}
+
+	n, err := w.WriteAt(p, w.offset)
+	w.offset += int64(n)
+
+	return n, err
+}
+
+// Listen create a gateway listening on conf.Addr
+func Listen(conf *Conf) (*Server, error) {
+	ln := conf.Listener
+	if ln == nil {
+		l, err := net.Listen("tcp", conf.Addr)
+		if err != nil {
+			return nil, err
+		}
+		ln = l
+	}
+
+	if conf.TLS != nil {
+		ln = tls.NewListener(ln, conf.TLS)
+	}
+
+	s := &Server{
+		conf:     conf,
+		storage:  conf.Storage,
+		limit:    ratelimit.NewByteBucket(conf.Limit),
+		clients:  ratelimit.NewGroup(conf.ClientLimit),
+		listener: ln,
+		log:      logger.Or(conf.Log),
+	}
+
+	s.log.Info("gateway started", "addr", ln.Addr().String())
+
+	if s.storage == nil {
+		s.storage = storage.NewLocal(protocol.DefaultDir)
+	}
+
+	s.http = &http.Server{
+		Handler:           s,
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+
+	return s, nil
+}
+
+// Start serve requests, it returns nil once the gateway is closed
+func (s *Server) Start() error {
+	err := s.http.Serve(s.listener)
+	if err == http.ErrServerClosed {
+		return nil
+	}
+
+	return err
+}
+
+// Close stop serving and close all connections
+func (s *Server) Close() error {
+	return s.http.Close()
+}
+
+// ServeHTTP authenticate client and serve upload or download
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log := s.log.With("remote", r.RemoteAddr, "method", r.Method)
+
+	client, err := s.authenticate(r)
+	if err != nil {
+		log.Error("authenticate error", "err", err)
+
+		direction := metrics.Send
+		if r.Method == http.MethodPut || r.Method == http.MethodPost {
+			direction = metrics.Receive
+		}
+		metrics.Reject(network, direction, metrics.ResultUnauthorized)
+
+		w.Header().Set("WWW-Authenticate", `Basic realm="redalert"`)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	key := client
+	if key == "" {
+		key = sourceIP(r.RemoteAddr)
+	} else {
+		log = log.With("client", client)
+	}
+	buckets := []*ratelimit.Bucket{s.limit, s.clients.Get(key)}
+
+	switch r.Method {
+	case http.MethodPut, http.MethodPost:
+		s.upload(w, r, log, client, buckets)
+	case http.MethodGet, http.MethodHead:
+		s.download(w, r, log, client, buckets)
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT, POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+// authenticate return ID of client by basic auth
+func (s *Server) authenticate(r *http.Request) (string, error) {
+	if s.conf.Auth == nil {
+		return "", nil
+	}
+
+	id, secret, ok := r.BasicAuth()
+	if !ok {
+		return "", auth.ErrRequired
+	}
+
+	if !s.conf.Auth.Match(id, []byte(secret)) {
+		return "", auth.ErrUnauthorized
+	}
+
+	return id, nil
+}
+
+// upload receive body of r as a file, it's committed once its size is
+// permitted and it matches Content-Digest
+func (s *Server) upload(w http.ResponseWriter, r *http.Request, log logger.Logger, client string, buckets []*ratelimit.Bucket) {
+	name, err := uploadName(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	log = log.With("file", name)
+
+	if err = s.conf.Policy.Check(client, policy.Write, name, r.ContentLength); err != nil {
+		metrics.Reject(network, metrics.Receive, metrics.ResultDenied)
+		deny(w, log, err)
+		return
+	}
+
+	v := newVerifier()
+	if header := r.Header.Get(digestHeader); header != "" {
+		if v.digests, err = parseDigest(header); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	object, err := s.storage.Create(name)
+	if err != nil {
+		log.Error("create file error", "err", err)
+
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	file := &objectWriter{
+		Object: object,
+		limit:  s.conf.Policy.MaxSize(client),
+	}
+
+	transfer := metrics.Start(network, metrics.Receive)
+	_, err = io.Copy(v.writer(io.MultiWriter(file, transfer)), ratelimit.NewReader(r.Body, buckets...))
+	if err == nil && v.digests == nil {
+		if trailer := r.Trailer.Get(digestHeader); trailer != "" {
+			v.digests, err = parseDigest(trailer)
+		}
+	}
+	if err == nil && (v.digests != nil || s.conf.RequireDigest) {
+		err = v.verify()
+	}
+
+	if err != nil {
+		log.Error("receive file error", "err", err)
+
+		object.Abort()
+		if err == policy.ErrTooLarge {
+			transfer.Done(metrics.ResultDenied)
+			deny(w, log, err)
+			return
+		}
+
+		result := metrics.ResultError
+		if err == ErrMismatch {
+			result = metrics.ResultHashMismatch
+		}
+		transfer.Done(result)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err = object.Commit(); err != nil {
+		log.Error("commit file error", "err", err)
+
+		transfer.Done(metrics.ResultError)
+
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	log.Info("receive file finish", "size", file.offset, "digest", v.digest())
+
+	transfer.Done(metrics.ResultOK)
+
+	w.Header().Set(digestHeader, v.digest())
+	w.WriteHeader(http.StatusCreated)
+}
+
+// uploadName return name of file to upload, it's the path of request, or
+// file name of Content-Disposition under the path if it ends with "/"
+func uploadName(r *http.Request) (string, error) {
+	name := r.URL.Path
+	if strings.HasSuffix(name, "/") {
+		_, params, err := mime.ParseMediaType(r.Header.Get("Content-Disposition"))
+		if err != nil || params["filename"] == "" {
+			return "", ErrName
+		}
+		name += path.Base(params["filename"])
+	}
+
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if name == "" {
+		return "", ErrName
+	}
+
+	return name, nil
+}
+
+// download send file of path of r from export, Content-Digest of it is a
+// header if the file can be read twice, otherwise a trailer
+func (s *Server) download(w http.ResponseWriter, r *http.Request, log logger.Logger, client string, buckets []*ratelimit.Bucket) {
+	name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+	if name == "" {
+		http.Error(w, ErrName.Error(), http.StatusBadRequest)
+		return
+	}
+	log = log.With("file", name)
+
+	if err := s.conf.Policy.Check(client, policy.Read, name, -1); err != nil {
+		metrics.Reject(network, metrics.Send, metrics.ResultDenied)
+		deny(w, log, err)
+		return
+	}
+
+	if s.conf.Export == nil {
+		metrics.Reject(network, metrics.Send, metrics.ResultDenied)
+		deny(w, log, errDownload)
+		return
+	}
+
+	file, err := s.conf.Export.Open(name)
+	if err != nil {
+		log.Error("open file error", "err", err)
+
+		if errors.Is(err, os.ErrNotExist) {
+			http.NotFound(w, r)
+			return
+		}
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+
+	header := w.Header()
+	header.Set("Content-Type", "application/octet-stream")
+
+	seeker, ok := file.(io.ReadSeeker)
+	if ok {
+		v := newVerifier()
+		size, err := io.Copy(v, seeker)
+		if err == nil {
+			_, err = seeker.Seek(0, io.SeekStart)
+		}
+		if err != nil {
+			log.Error("read file error", "err", err)
+
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		header.Set("Content-Length", strconv.FormatInt(size, 10))
+		header.Set(digestHeader, v.digest())
+	} else {
+		header.Set("Trailer", digestHeader)
+	}
+
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	log.Info("serve file")
+
+	transfer := metrics.Start(network, metrics.Send)
+
+	var v *verifier
+	out := io.MultiWriter(ratelimit.NewWriter(w, buckets...), transfer)
+	if !ok {
+		v = newVerifier()
+		out = v.writer(out)
+	}
+
+	if _, err = io.Copy(out, file); err != nil {
+		log.Error("serve file error", "err", err)
+
+		transfer.Done(metrics.ResultError)
+		return
+	}
+
+	if v != nil {
+		header.Set(digestHeader, v.digest())
+	}
+	transfer.Done(metrics.ResultOK)
+}
+
+// deny reply request denied by policy
+func deny(w http.ResponseWriter, log logger.Logger, err error) {
+	log.Warn("deny request", "err", err)
+
+	status := http.StatusForbidden
+	if err == policy.ErrTooLarge {
+		status = http.StatusRequestEntityTooLarge
+	}
+	http.Error(w, err.Error(), status)
+}
+
+// sourceIP return IP of remote address host:port
+func sourceIP(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+
+	return host
+}
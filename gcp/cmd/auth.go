@@ -0,0 +1,68 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/TechCatsLab/redalert/auth"
+)
+
+var (
+	authFile string
+	authID   string
+)
+
+// serverKeyring return keyring of clients, nil if authentication is disabled
+func serverKeyring() (auth.Keyring, error) {
+	if authFile == "" {
+		return nil, nil
+	}
+
+	return auth.LoadKeyring(authFile)
+}
+
+// clientKey return key to answer auth challenge of server, nil if not set
+func clientKey() (*auth.Key, error) {
+	if authFile == "" {
+		return nil, nil
+	}
+
+	return auth.LoadKey(authFile, authID)
+}
+
+func addServerAuthFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&authFile, "auth-file", "", "keyfile of clients, lines of \"<id> <secret>\", requests not authenticated are rejected.")
+}
+
+func addClientAuthFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&authFile, "auth-file", "", "keyfile to answer auth challenge of server, lines of \"<id> <secret>\"")
+	cmd.Flags().StringVar(&authID, "auth-id", "", "client ID in keyfile, the first one if empty")
+}
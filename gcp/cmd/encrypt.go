@@ -0,0 +1,78 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	encrypt bool
+	pskFile string
+
+	errEncryptOnlyUDP = errors.New("encryption is only supported by udp and unixgram, use TLS for tcp and ws")
+)
+
+// encryptConfig return if udp packets should be encrypted and the pre-shared
+// key, which implies encryption
+func encryptConfig() (bool, []byte, error) {
+	if !encrypt && pskFile == "" {
+		return false, nil, nil
+	}
+
+	if isStream(protocol) {
+		return false, nil, errEncryptOnlyUDP
+	}
+
+	if pskFile == "" {
+		return true, nil, nil
+	}
+
+	psk, err := os.ReadFile(pskFile)
+	if err != nil {
+		return false, nil, err
+	}
+
+	psk = bytes.TrimSpace(psk)
+	if len(psk) == 0 {
+		return false, nil, errors.New("Empty pre-shared key in " + pskFile)
+	}
+
+	return true, psk, nil
+}
+
+func addEncryptFlags(cmd *cobra.Command, usage string) {
+	cmd.Flags().BoolVar(&encrypt, "encrypt", false, usage)
+	cmd.Flags().StringVar(&pskFile, "psk-file", "", "pre-shared key file of encrypted udp, implies --encrypt")
+}
@@ -0,0 +1,227 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	holes "github.com/TechCatsLab/redalert/sparse"
+	"github.com/spf13/cobra"
+)
+
+var errTargetTimeout = errors.New("No result within --target-timeout")
+
+var (
+	targetList    string
+	targetsFile   string
+	parallel      int
+	targetTimeout time.Duration
+)
+
+// result is the result of sending to a target
+type result struct {
+	target string
+	err    error
+	took   time.Duration
+}
+
+// addFanoutFlags add flags of sending to many receivers to cmd
+func addFanoutFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&targetList, "to", "", "Receivers as host:port,host:port instead of --host, port is --port if omitted")
+	cmd.Flags().StringVar(&targetsFile, "targets", "", "File of receivers, a host:port a line, # starts a comment")
+	cmd.Flags().IntVar(&parallel, "parallel", 4, "Receivers to send to at a time")
+	cmd.Flags().DurationVar(&targetTimeout, "target-timeout", time.Hour, "Time to wait for the result of a receiver, no limit if 0")
+}
+
+// loadTargets return receivers of --to and --targets, port is the port of a
+// receiver without one
+func loadTargets(port string) ([]string, error) {
+	lines := strings.Split(targetList, ",")
+
+	if targetsFile != "" {
+		data, err := os.ReadFile(targetsFile)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, line := range strings.Split(string(data), "\n") {
+			if i := strings.Index(line, "#"); i >= 0 {
+				line = line[:i]
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	var targets []string
+	for _, t := range lines {
+		if t = strings.TrimSpace(t); t == "" {
+			continue
+		}
+
+		if _, _, err := net.SplitHostPort(t); err != nil {
+			t = net.JoinHostPort(strings.Trim(t, "[]"), port)
+		}
+		targets = append(targets, t)
+	}
+
+	return targets, nil
+}
+
+// fanout call send for every target, parallel at a time, and print results.
+// A target succeeds once send returns nil, which it does only when the
+// receiver replies that the file is kept, and fails if that takes longer
+// than timeout. It tells if all targets succeeded.
+func fanout(targets []string, parallel int, timeout time.Duration, send func(target string) error) bool {
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	var (
+		results = make([]result, len(targets))
+		slots   = make(chan struct{}, parallel)
+		wg      sync.WaitGroup
+	)
+
+	for i, target := range targets {
+		wg.Add(1)
+		slots <- struct{}{}
+
+		go func(i int, target string) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+
+			begin := time.Now()
+			err := wait(timeout, func() error { return send(target) })
+			results[i] = result{target: target, err: err, took: time.Since(begin)}
+		}(i, target)
+	}
+	wg.Wait()
+
+	done := 0
+	fmt.Println("[SUMMARY]")
+	for _, r := range results {
+		if r.err != nil {
+			fmt.Printf("%s\tFAILED\t%v\n", r.target, r.err)
+			continue
+		}
+
+		fmt.Printf("%s\tOK\t%v\n", r.target, r.took.Round(time.Millisecond))
+		done++
+	}
+	fmt.Printf("%d of %d receivers succeeded\n", done, len(results))
+
+	return done == len(results)
+}
+
+// wait return the result of f, or errTargetTimeout if it takes longer than
+// timeout. f is left running then, its result is dropped.
+func wait(timeout time.Duration, f func() error) error {
+	if timeout <= 0 {
+		return f()
+	}
+
+	result := make(chan error, 1)
+	go func() {
+		result <- f()
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(timeout):
+		return errTargetTimeout
+	}
+}
+
+// spool copy source, or stdin if it's "-", to a temp file, so it's read
+// once and sent to many receivers, holes of source are kept. The caller
+// removes the file.
+func spool(source string) (string, error) {
+	file, err := os.CreateTemp("", "gcp-")
+	if err != nil {
+		return "", err
+	}
+
+	err = copySource(file, source)
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+
+	return file.Name(), nil
+}
+
+// copySource copy source, or stdin if it's "-", to file
+func copySource(file *os.File, source string) error {
+	if source == "-" {
+		_, err := io.Copy(file, os.Stdin)
+		return err
+	}
+
+	src, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	r, err := holes.NewReader(src)
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, 1<<20)
+	offset := int64(0)
+	for {
+		n, hole, err := r.Next(buf)
+		if err == io.EOF {
+			return file.Truncate(offset)
+		}
+		if err != nil {
+			return err
+		}
+
+		if _, err = file.WriteAt(buf[:n], offset); err != nil {
+			return err
+		}
+		offset += int64(n) + hole
+	}
+}
@@ -0,0 +1,167 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/TechCatsLab/redalert/storage"
+	"github.com/TechCatsLab/redalert/transport"
+)
+
+// getCmd represents the get command
+var getCmd = &cobra.Command{
+	Use:   "get host:path [local]",
+	Short: "Download a file from server.",
+	Long: `get downloads a file from the dir exported by a server, and saves it to
+local, which is the base name of path by default, or "-" for stdout.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 1 {
+			cmd.Help()
+			return
+		}
+
+		remoteHost, remotePath, ok := splitRemote(args[0])
+		if !ok {
+			cmd.Help()
+			return
+		}
+
+		local := path.Base(remotePath)
+		if len(args) > 1 {
+			local = args[1]
+		}
+
+		var dst storage.Storage = storage.NewLocal(".")
+		if local == "-" {
+			dst = &storage.Writer{W: os.Stdout}
+		}
+
+		tlsConf, err := clientTLSConfig()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "TLS config error:", err)
+			os.Exit(1)
+		}
+
+		secure, psk, err := encryptConfig()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Encrypt config error:", err)
+			os.Exit(1)
+		}
+
+		key, err := clientKey()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Auth config error:", err)
+			os.Exit(1)
+		}
+
+		bandwidth, _, err := limitConfig()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Limit config error:", err)
+			os.Exit(1)
+		}
+
+		codecID, err := codecConfig()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Compress config error:", err)
+			os.Exit(1)
+		}
+
+		t, err := transportOf(protocol)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Get error:", err)
+			os.Exit(1)
+		}
+
+		addr, err := addrOf(protocol, remoteHost, port)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Get error:", err)
+			os.Exit(1)
+		}
+
+		err = t.Fetch(remotePath, dst, local, &transport.Conf{
+			Addr:     addr,
+			PackSize: packSize,
+			Key:      key,
+			Limit:    bandwidth,
+			Codec:    codecID,
+			TLS:      tlsConf,
+			Secure:   secure,
+			PSK:      psk,
+		})
+		if e := writeMetrics(); e != nil {
+			fmt.Fprintln(os.Stderr, "Metrics error:", e)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Get error:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// splitRemote split host:path, host may be an IPv6 address in brackets
+func splitRemote(remote string) (string, string, bool) {
+	if strings.HasPrefix(remote, "[") {
+		end := strings.Index(remote, "]:")
+		if end < 0 {
+			return "", "", false
+		}
+
+		return remote[1:end], remote[end+2:], true
+	}
+
+	i := strings.Index(remote, ":")
+	if i < 0 {
+		return "", "", false
+	}
+
+	return remote[:i], remote[i+1:], true
+}
+
+func init() {
+	RootCmd.AddCommand(getCmd)
+
+	getCmd.Flags().StringVarP(&protocol, "proto", "o", "udp", "download method, one of registered transports such as udp, tcp, unix or ws")
+	getCmd.Flags().StringVarP(&port, "port", "p", "17120", "Target port")
+	getCmd.Flags().IntVarP(&packSize, "packetSize", "s", 0, "Every packet size, probed on udp and 1024 on tcp if 0")
+	addClientTLSFlags(getCmd)
+	addEncryptFlags(getCmd, "Encrypt udp packets")
+	addClientAuthFlags(getCmd)
+	addClientLimitFlags(getCmd)
+	addCompressFlags(getCmd)
+	addSocketFlag(getCmd)
+	addWSPathFlag(getCmd)
+	addClientMetricsFlags(getCmd)
+}
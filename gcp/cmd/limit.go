@@ -0,0 +1,71 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/TechCatsLab/redalert/ratelimit"
+)
+
+var (
+	limit       string
+	clientLimit string
+)
+
+// limitConfig return limit of all transfers and of a client in bytes per
+// second, 0 for no limit
+func limitConfig() (float64, float64, error) {
+	var all, client float64
+	var err error
+
+	if limit != "" {
+		if all, err = ratelimit.ParseRate(limit); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	if clientLimit != "" {
+		if client, err = ratelimit.ParseRate(clientLimit); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	return all, client, nil
+}
+
+func addServerLimitFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&limit, "limit", "", "bandwidth of all transfers, such as 50MB/s, no limit if empty.")
+	cmd.Flags().StringVar(&clientLimit, "client-limit", "", "bandwidth of a client, by ID or source IP, by ID only on a unix socket, such as 5MB/s, no limit if empty.")
+}
+
+func addClientLimitFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&limit, "limit", "", "Bandwidth of transfer, such as 50MB/s, no limit if empty")
+}
@@ -0,0 +1,107 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package cmd
+
+import (
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/TechCatsLab/redalert/metrics"
+)
+
+var (
+	metricsAddr string
+	metricsFile string
+)
+
+// serveMetrics serve metrics at /metrics of metricsAddr in background, it
+// does nothing if metricsAddr is empty
+func serveMetrics() error {
+	if metricsAddr == "" {
+		return nil
+	}
+
+	ln, err := net.Listen("tcp", metricsAddr)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Default)
+	srv := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go srv.Serve(ln)
+
+	return nil
+}
+
+// writeMetrics write metrics to metricsFile if it's set, by a temporary
+// file renamed to it, so a collector never reads a partial file
+func writeMetrics() error {
+	if metricsFile == "" {
+		return nil
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(metricsFile), "."+filepath.Base(metricsFile)+".tmp-*")
+	if err != nil {
+		return err
+	}
+
+	_, err = metrics.Default.WriteTo(tmp)
+	if e := tmp.Close(); err == nil {
+		err = e
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), metricsFile)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+
+	return err
+}
+
+func addServerMetricsFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&metricsAddr, "metrics", "", "host:port to serve Prometheus metrics at /metrics, disabled if empty.")
+}
+
+func addClientMetricsFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&metricsFile, "metrics-file", "", "File to write Prometheus metrics to when done, such as for the textfile collector of node exporter")
+}
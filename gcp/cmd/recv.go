@@ -0,0 +1,95 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/TechCatsLab/redalert/storage"
+	"github.com/TechCatsLab/redalert/transport"
+)
+
+// recvCmd represents the recv command
+var recvCmd = &cobra.Command{
+	Use:   "recv",
+	Short: "Receive a single file and write it to stdout.",
+	Long: `recv waits for one incoming transfer and writes its content to stdout,
+so it can be piped into other tools. It exits once the transfer finished.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		done := make(chan error, 1)
+		stdout := &storage.Writer{
+			W: os.Stdout,
+			Done: func(name string, err error) {
+				done <- err
+			},
+		}
+
+		t, err := transportOf(protocol)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Receive error:", err)
+			os.Exit(1)
+		}
+
+		addr, err := addrOf(protocol, serverAddress, serverPort)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Receive error:", err)
+			os.Exit(1)
+		}
+
+		go func() {
+			err := t.Serve(&transport.ServerConf{
+				Addr:       addr,
+				Storage:    stdout,
+				MaxConn:    1,
+				CacheCount: serverCacheSize,
+			})
+			if err != nil {
+				done <- err
+			}
+		}()
+
+		if err := <-done; err != nil {
+			fmt.Fprintln(os.Stderr, "Receive error:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(recvCmd)
+
+	recvCmd.Flags().StringVarP(&protocol, "protocol", "o", "udp", "select a transport to receive file, one of registered ones such as udp, tcp or unix.")
+	recvCmd.Flags().StringVarP(&serverAddress, "addr", "a", "127.0.0.1", "addr of server.")
+	recvCmd.Flags().StringVarP(&serverPort, "port", "p", "17120", "port of server.")
+	addSocketFlag(recvCmd)
+}
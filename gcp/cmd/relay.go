@@ -0,0 +1,112 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package cmd
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/TechCatsLab/redalert/auth"
+)
+
+var errNoRelayHops = errors.New("--relay requires --relay-hops")
+
+var (
+	relay         bool
+	relayHops     string
+	relayTLS      bool
+	relayCA       string
+	relayAuthFile string
+	relayAuthID   string
+	route         string
+)
+
+// relayConfig return TLS config and key to connect next hops, nil if not set
+func relayConfig() (*tls.Config, *auth.Key, error) {
+	var (
+		conf *tls.Config
+		key  *auth.Key
+		err  error
+	)
+
+	if relayTLS || relayCA != "" {
+		conf = &tls.Config{
+			MinVersion: tls.VersionTLS12,
+		}
+
+		if relayCA != "" {
+			if conf.RootCAs, err = loadCertPool(relayCA); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	if relayAuthFile != "" {
+		if key, err = auth.LoadKey(relayAuthFile, relayAuthID); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return conf, key, nil
+}
+
+// relayHopList return next hops of --relay-hops, a relay must have some
+func relayHopList() ([]string, error) {
+	var hops []string
+	for _, hop := range strings.Split(relayHops, ",") {
+		if hop = strings.TrimSpace(hop); hop == "" {
+			continue
+		}
+
+		if _, _, err := net.SplitHostPort(hop); err != nil {
+			return nil, err
+		}
+		hops = append(hops, hop)
+	}
+
+	if relay && len(hops) == 0 {
+		return nil, errNoRelayHops
+	}
+
+	return hops, nil
+}
+
+func addRelayFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&relay, "relay", false, "forward tcp files sent with --route to the next hop instead of storing them.")
+	cmd.Flags().StringVar(&relayHops, "relay-hops", "", "next hops a relay may connect as host:port,host:port, routes to others are denied.")
+	cmd.Flags().BoolVar(&relayTLS, "relay-tls", false, "connect next hops by TLS.")
+	cmd.Flags().StringVar(&relayCA, "relay-ca", "", "CA file to verify next hops, default to system roots, implies --relay-tls.")
+	cmd.Flags().StringVar(&relayAuthFile, "relay-auth-file", "", "keyfile to answer auth challenge of next hops for authenticated clients.")
+	cmd.Flags().StringVar(&relayAuthID, "relay-auth-id", "", "ID in relay keyfile, the first one if empty.")
+}
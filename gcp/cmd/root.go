@@ -36,9 +36,15 @@ import (
 	"github.com/mitchellh/go-homedir"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+
+	"github.com/TechCatsLab/redalert/logger"
 )
 
-var cfgFile string
+var (
+	cfgFile   string
+	logLevel  string
+	logFormat string
+)
 
 // RootCmd represents the base command when called without any subcommands
 var RootCmd = &cobra.Command{
@@ -61,12 +67,14 @@ func Execute() {
 }
 
 func init() {
-	cobra.OnInitialize(initConfig)
+	cobra.OnInitialize(initConfig, initLogger)
 
 	// Here you will define your flags and configuration settings.
 	// Cobra supports persistent flags, which, if defined here,
 	// will be global for your application.
 	RootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.cobra.yaml)")
+	RootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "info", "Log messages of level and above, debug, info, warn or error")
+	RootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "text", "Log format, text or json")
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
@@ -98,3 +106,20 @@ func initConfig() {
 		fmt.Println("Using config file:", viper.ConfigFileUsed())
 	}
 }
+
+// initLogger make logs of --log-level and --log-format go to stderr
+func initLogger() {
+	level, err := logger.ParseLevel(logLevel)
+	if err != nil {
+		fmt.Println("Log level error:", err)
+		os.Exit(1)
+	}
+
+	l, err := logger.New(os.Stderr, level, logFormat)
+	if err != nil {
+		fmt.Println("Log format error:", err)
+		os.Exit(1)
+	}
+
+	logger.SetDefault(l)
+}
@@ -30,17 +30,35 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
 
-	tcp "github.com/TechCatsLab/redalert/tcp/client"
+	"github.com/TechCatsLab/redalert/transport"
 	"github.com/TechCatsLab/redalert/udp/client"
 	"github.com/spf13/cobra"
 )
 
+var (
+	errRouteOnlyStream = errors.New("--route is only supported by tcp, unix and ws")
+	errDeltaOnlyStream = errors.New("--delta is only supported by tcp, unix and ws")
+	errFECOnlyDatagram = errors.New("--fec is only supported by udp and unixgram")
+	errMulticastFlags  = errors.New("--fec, --compress, --skip-same, --sparse and --auth-file are not supported by multicast")
+)
+
 var (
 	host     string
 	port     string
 	packSize int
+	dest     string
+	sendDiff bool
+	skipSame bool
+	sparse   bool
+	fecGroup int
+	mcastAck int
 )
 
 // sendCmd represents the send command
@@ -54,45 +72,238 @@ var sendCmd = &cobra.Command{
 			return
 		}
 
-		if protocol == "tcp" {
-			conf := &tcp.Conf{
-				Address:  host,
-				Port:     port,
-				PackSize: packSize,
-				FileName: args[0],
+		// exit with error after deferred cleanup
+		failed := false
+		defer func() {
+			if failed {
+				os.Exit(1)
+			}
+		}()
+
+		tlsConf, err := clientTLSConfig()
+		if err != nil {
+			fmt.Println("TLS config error:", err)
+			failed = true
+			return
+		}
+
+		secure, psk, err := encryptConfig()
+		if err != nil {
+			fmt.Println("Encrypt config error:", err)
+			failed = true
+			return
+		}
+
+		key, err := clientKey()
+		if err != nil {
+			fmt.Println("Auth config error:", err)
+			failed = true
+			return
+		}
+
+		defer func() {
+			if err := writeMetrics(); err != nil {
+				fmt.Println("Metrics error:", err)
 			}
+		}()
+
+		source := args[0]
+		if source == "-" && (dest == "" || strings.HasSuffix(dest, "/")) {
+			fmt.Println("Send error: --dest must be a file name to send stdin")
+			failed = true
+			return
+		}
+
+		// dest ends with "/" is a dir on server, the base name of file if
+		// it's empty
+		remotePath := dest
+		if remotePath == "" || strings.HasSuffix(remotePath, "/") {
+			remotePath += filepath.Base(source)
+		}
+
+		bandwidth, _, err := limitConfig()
+		if err != nil {
+			fmt.Println("Limit config error:", err)
+			failed = true
+			return
+		}
 
-			cli := tcp.NewClient(conf)
-			cli.Start()
+		if err = checkSendFlags(protocol); err != nil {
+			fmt.Println("Send error:", err)
+			failed = true
+			return
 		}
 
-		conf := &client.Conf{
-			RemoteAddress: host,
-			RemotePort:    port,
-			PacketSize:    packSize,
-			FileName:      args[0],
+		codecID, err := codecConfig()
+		if err != nil {
+			fmt.Println("Compress config error:", err)
+			failed = true
+			return
 		}
 
-		cli, err := client.NewClient(conf)
+		t, err := transportOf(protocol)
 		if err != nil {
-			fmt.Println("Remote server not receive:", err)
+			fmt.Println("Send error:", err)
+			failed = true
+			return
+		}
+
+		conf := transport.Conf{
+			PackSize: packSize,
+			Dest:     remotePath,
+			Key:      key,
+			Limit:    bandwidth,
+			Codec:    codecID,
+			SkipSame: skipSame,
+			Sparse:   sparse,
+			TLS:      tlsConf,
+			Delta:    sendDiff,
+			Route:    route,
+			Secure:   secure,
+			PSK:      psk,
+			FEC:      fecGroup,
+		}
+
+		fan := targetList != "" || targetsFile != ""
+
+		var targets []string
+		if fan {
+			if isSocket(protocol) {
+				fmt.Println("Send error: fan-out is not supported by unix transports")
+				failed = true
+				return
+			}
+
+			if targets, err = loadTargets(port); err != nil {
+				fmt.Println("Targets error:", err)
+				failed = true
+				return
+			}
+
+			if protocol == transport.WS {
+				for i := range targets {
+					targets[i] += "/" + strings.TrimPrefix(wsPath, "/")
+				}
+			}
+		}
+
+		// source is read once, every receiver is sent the same spooled copy
+		if source == "-" || fan {
+			if source, err = spool(source); err != nil {
+				fmt.Println("Read source error:", err)
+				failed = true
+				return
+			}
+			defer os.Remove(source)
+		}
+
+		send := func(target string) error {
+			c := conf
+			c.Addr = target
+			return t.Send(source, &c)
+		}
+
+		if fan {
+			failed = !fanout(targets, parallel, targetTimeout, send)
+			return
+		}
+
+		if ip := net.ParseIP(host); ip != nil && ip.IsMulticast() && protocol == transport.UDP {
+			if fecGroup != 0 || codecID != 0 || skipSame || sparse || key != nil {
+				fmt.Println("Send error:", errMulticastFlags)
+				failed = true
+				return
+			}
+
+			failed = !multicast(&client.Conf{
+				RemoteAddress: host,
+				RemotePort:    port,
+				PacketSize:    packSize,
+				FileName:      source,
+				Secure:        secure,
+				PSK:           psk,
+				Dest:          remotePath,
+				Limit:         bandwidth,
+				Receivers:     mcastAck,
+			})
 			return
 		}
 
-		err = cli.Start()
+		addr, err := addrOf(protocol, host, port)
 		if err != nil {
-			fmt.Println("Client run error:", err)
+			fmt.Println("Send error:", err)
+			failed = true
 			return
 		}
+
+		if err = send(addr); err != nil {
+			fmt.Println("Send error:", err)
+			failed = true
+		}
 	},
 }
 
+// checkSendFlags return error of a flag set which transport name doesn't
+// support, so it's not ignored silently
+func checkSendFlags(name string) error {
+	switch {
+	case route != "" && !isStream(name):
+		return errRouteOnlyStream
+	case sendDiff && !isStream(name):
+		return errDeltaOnlyStream
+	case fecGroup != 0 && isStream(name):
+		return errFECOnlyDatagram
+	}
+
+	return nil
+}
+
+// multicast send file to a multicast group and print reports of receivers,
+// it tells if all receivers are done
+func multicast(conf *client.Conf) bool {
+	reports, err := client.Multicast(conf)
+
+	done := 0
+	for _, r := range reports {
+		if r.Err != nil {
+			fmt.Println(r.Addr, "failed:", r.Err)
+			continue
+		}
+
+		fmt.Println(r.Addr, "done")
+		done++
+	}
+	fmt.Printf("%d of %d receivers done\n", done, len(reports))
+
+	if err != nil {
+		fmt.Println("Multicast error:", err)
+	}
+
+	return err == nil && done == len(reports)
+}
+
 func init() {
 	RootCmd.AddCommand(sendCmd)
 
 	// Here you will define your flags and configuration settings.
-	sendCmd.Flags().StringVarP(&protocol, "proto", "o", "udp", "send method")
+	sendCmd.Flags().StringVarP(&protocol, "proto", "o", "udp", "send method, one of registered transports such as udp, tcp, unix or ws")
 	sendCmd.Flags().StringVarP(&host, "host", "H", "127.0.0.1", "Target host")
 	sendCmd.Flags().StringVarP(&port, "port", "p", "17120", "Target port")
-	sendCmd.Flags().IntVarP(&packSize, "packetSize", "s", 1024, "Every packet size")
+	sendCmd.Flags().IntVarP(&packSize, "packetSize", "s", 0, "Every packet size, probed on udp and 1024 on tcp if 0")
+	addClientTLSFlags(sendCmd)
+	addEncryptFlags(sendCmd, "Encrypt udp packets")
+	addClientAuthFlags(sendCmd)
+	addClientLimitFlags(sendCmd)
+	addCompressFlags(sendCmd)
+	sendCmd.Flags().StringVarP(&dest, "dest", "d", "", "Path on server, a dir if ends with \"/\", base name of file if empty")
+	sendCmd.Flags().BoolVar(&sendDiff, "delta", false, "Send only changes if server has an old copy of file, tcp only")
+	sendCmd.Flags().BoolVar(&skipSame, "skip-same", false, "Send nothing if server has the same file already")
+	sendCmd.Flags().BoolVar(&sparse, "sparse", false, "Send holes of file as their length, not with compression or delta on tcp")
+	sendCmd.Flags().IntVar(&fecGroup, "fec", 0, "Send a parity pack every N packs on udp, one lost pack of a group is recovered without resend")
+	sendCmd.Flags().StringVar(&route, "route", "", "Next hops after host as host:port,host:port, host relays file to the last one, tcp only")
+	addFanoutFlags(sendCmd)
+	addSocketFlag(sendCmd)
+	addWSPathFlag(sendCmd)
+	addClientMetricsFlags(sendCmd)
+	sendCmd.Flags().IntVar(&mcastAck, "receivers", 0, "Receivers to wait for if host is a multicast group, until they're quiet if 0")
 }
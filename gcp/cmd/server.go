@@ -30,10 +30,14 @@
 package cmd
 
 import (
+	"fmt"
+
 	"github.com/spf13/cobra"
 
-	tcp "github.com/TechCatsLab/redalert/tcp/server"
-	"github.com/TechCatsLab/redalert/udp/server"
+	"github.com/TechCatsLab/redalert/gateway"
+	"github.com/TechCatsLab/redalert/policy"
+	"github.com/TechCatsLab/redalert/storage"
+	"github.com/TechCatsLab/redalert/transport"
 )
 
 var (
@@ -43,6 +47,14 @@ var (
 	serverPackSize  int
 	serverCacheSize int
 	maxConn         int
+	exportDir       string
+	policyFile      string
+	maxConnPerIP    int
+	connRate        float64
+	groupAddr       string
+	groupIface      string
+	httpAddr        string
+	httpDigest      bool
 )
 
 // serverCmd represents the server command
@@ -56,24 +68,125 @@ Cobra is a CLI library for Go that empowers applications.
 This application is a tool to generate the needed files
 to quickly create a Cobra application.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		if protocol == "tcp" {
-			tcpConf := tcp.Conf{
-				Addr:    serverAddress,
-				Port:    serverPort,
-				MaxConn: maxConn,
+		var export storage.Source
+		if exportDir != "" {
+			export = storage.NewLocal(exportDir)
+		}
+
+		if wsAddr != "" {
+			protocol = transport.WS
+		}
+
+		tlsConf, err := serverTLSConfig()
+		if err != nil {
+			fmt.Println("TLS config error:", err)
+			return
+		}
+
+		secure, psk, err := encryptConfig()
+		if err != nil {
+			fmt.Println("Encrypt config error:", err)
+			return
+		}
+
+		keyring, err := serverKeyring()
+		if err != nil {
+			fmt.Println("Auth config error:", err)
+			return
+		}
+
+		bandwidth, clientBandwidth, err := limitConfig()
+		if err != nil {
+			fmt.Println("Limit config error:", err)
+			return
+		}
+
+		relayTLSConf, relayKey, err := relayConfig()
+		if err != nil {
+			fmt.Println("Relay config error:", err)
+			return
+		}
+
+		hops, err := relayHopList()
+		if err != nil {
+			fmt.Println("Relay config error:", err)
+			return
+		}
+
+		var rules *policy.Policy
+		if policyFile != "" {
+			if rules, err = policy.Load(policyFile); err != nil {
+				fmt.Println("Policy config error:", err)
+				return
+			}
+		}
+
+		t, err := transportOf(protocol)
+		if err != nil {
+			fmt.Println("Server error:", err)
+			return
+		}
+
+		addr := wsAddr
+		if addr == "" {
+			if addr, err = addrOf(protocol, serverAddress, serverPort); err != nil {
+				fmt.Println("Server error:", err)
+				return
 			}
+		}
+
+		if err = serveMetrics(); err != nil {
+			fmt.Println("Metrics error:", err)
+			return
+		}
 
-			server := tcp.NewServer(&tcpConf)
-			server.Start()
-		} else {
-			conf := server.Conf{
-				Address:    serverAddress,
-				Port:       serverPort,
-				CacheCount: serverCacheSize,
+		if httpAddr != "" {
+			gw, err := gateway.Listen(&gateway.Conf{
+				Addr:          httpAddr,
+				Export:        export,
+				TLS:           tlsConf,
+				Auth:          keyring,
+				Policy:        rules,
+				Limit:         bandwidth,
+				ClientLimit:   clientBandwidth,
+				RequireDigest: httpDigest,
+			})
+			if err != nil {
+				fmt.Println("Gateway error:", err)
+				return
 			}
 
-			server := server.NewServer(&conf)
-			server.HandleClient()
+			go func() {
+				if err := gw.Start(); err != nil {
+					fmt.Println("Gateway error:", err)
+				}
+			}()
+		}
+
+		err = t.Serve(&transport.ServerConf{
+			Addr:        addr,
+			Export:      export,
+			Auth:        keyring,
+			Policy:      rules,
+			Limit:       bandwidth,
+			ClientLimit: clientBandwidth,
+
+			TLS:          tlsConf,
+			MaxConn:      maxConn,
+			MaxConnPerIP: maxConnPerIP,
+			ConnRate:     connRate,
+			Relay:        relay,
+			RelayHops:    hops,
+			RelayTLS:     relayTLSConf,
+			RelayKey:     relayKey,
+			CacheCount:   serverCacheSize,
+			Secure:       secure,
+			PSK:          psk,
+			Group:        groupAddr,
+			Interface:    groupIface,
+		})
+		if err != nil {
+			fmt.Println("Server error:", err)
 		}
 	},
 }
@@ -81,10 +194,27 @@ to quickly create a Cobra application.`,
 func init() {
 	RootCmd.AddCommand(serverCmd)
 
-	serverCmd.Flags().StringVarP(&protocol, "protocol", "o", "udp", "select a proto to send file, udp or tcp.")
+	serverCmd.Flags().StringVarP(&protocol, "protocol", "o", "udp", "select a transport to receive files, one of registered ones such as udp, tcp, unix or ws.")
 	serverCmd.Flags().StringVarP(&serverAddress, "addr", "a", "127.0.0.1", "addr of server.")
 	serverCmd.Flags().StringVarP(&serverPort, "port", "p", "17120", "port of server.")
 	serverCmd.Flags().IntVarP(&serverPackSize, "pack", "P", 1024, "size of pack.")
 	serverCmd.Flags().IntVarP(&serverCacheSize, "cache", "c", 1024, "size of cache.")
 	serverCmd.Flags().IntVarP(&maxConn, "max", "M", 10, "TCP max connection.")
+	serverCmd.Flags().IntVar(&maxConnPerIP, "max-per-ip", 0, "TCP max connection of a source IP, no limit if 0 or on a unix socket.")
+	serverCmd.Flags().Float64Var(&connRate, "conn-rate", 0, "TCP new connections per second of a source IP, no limit if 0 or on a unix socket.")
+	serverCmd.Flags().StringVarP(&exportDir, "export", "e", "", "dir of files can be downloaded, download is disabled if empty.")
+	addServerTLSFlags(serverCmd)
+	addEncryptFlags(serverCmd, "reject udp packets not encrypted.")
+	addServerAuthFlags(serverCmd)
+	addServerLimitFlags(serverCmd)
+	serverCmd.Flags().StringVar(&groupAddr, "group", "", "udp multicast group to receive files from, such as 239.0.0.1:17130.")
+	serverCmd.Flags().StringVar(&groupIface, "iface", "", "network interface to join multicast group on.")
+	addRelayFlags(serverCmd)
+	addSocketFlag(serverCmd)
+	addWSPathFlag(serverCmd)
+	serverCmd.Flags().StringVar(&wsAddr, "ws", "", "serve the tcp protocol over WebSocket at host:port/path such as :8080/redalert, wss with TLS flags.")
+	serverCmd.Flags().StringVar(&httpAddr, "http", "", "serve HTTP uploads by PUT or POST and downloads by GET at host:port too, HTTPS with TLS flags.")
+	serverCmd.Flags().BoolVar(&httpDigest, "http-digest", false, "reject HTTP uploads without Content-Digest.")
+	addServerMetricsFlags(serverCmd)
+	serverCmd.Flags().StringVar(&policyFile, "policy", "", "JSON file of what clients may do, everything is permitted if empty.")
 }
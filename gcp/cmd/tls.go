@@ -0,0 +1,141 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package cmd
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"os"
+
+	"github.com/TechCatsLab/redalert/transport"
+	"github.com/spf13/cobra"
+)
+
+var (
+	tlsEnable bool
+	tlsCert   string
+	tlsKey    string
+	tlsCA     string
+
+	errTLSOnlyTCP = errors.New("TLS is only supported by tcp, ws and the HTTP gateway")
+)
+
+// loadCertPool read PEM encoded certificates from file
+func loadCertPool(file string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.New("No certificate found in " + file)
+	}
+
+	return pool, nil
+}
+
+// serverTLSConfig return nil if no certificate given, client certificates
+// are required and verified if CA file given.
+func serverTLSConfig() (*tls.Config, error) {
+	if tlsCert == "" && tlsKey == "" && tlsCA == "" {
+		return nil, nil
+	}
+
+	if protocol != transport.TCP && protocol != transport.WS && httpAddr == "" {
+		return nil, errTLSOnlyTCP
+	}
+
+	cert, err := tls.LoadX509KeyPair(tlsCert, tlsKey)
+	if err != nil {
+		return nil, err
+	}
+
+	conf := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if tlsCA != "" {
+		if conf.ClientCAs, err = loadCertPool(tlsCA); err != nil {
+			return nil, err
+		}
+		conf.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return conf, nil
+}
+
+// clientTLSConfig return nil if TLS not enabled, server certificate is
+// verified by CA file if given, otherwise by system roots.
+func clientTLSConfig() (*tls.Config, error) {
+	if !tlsEnable && tlsCert == "" && tlsCA == "" {
+		return nil, nil
+	}
+
+	if protocol != transport.TCP && protocol != transport.WS {
+		return nil, errTLSOnlyTCP
+	}
+
+	conf := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	var err error
+	if tlsCA != "" {
+		if conf.RootCAs, err = loadCertPool(tlsCA); err != nil {
+			return nil, err
+		}
+	}
+
+	if tlsCert != "" {
+		cert, err := tls.LoadX509KeyPair(tlsCert, tlsKey)
+		if err != nil {
+			return nil, err
+		}
+		conf.Certificates = []tls.Certificate{cert}
+	}
+
+	return conf, nil
+}
+
+func addServerTLSFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&tlsCert, "cert", "", "TLS certificate file, enable TLS for tcp, wss for ws and HTTPS for the HTTP gateway.")
+	cmd.Flags().StringVar(&tlsKey, "key", "", "TLS private key file.")
+	cmd.Flags().StringVar(&tlsCA, "ca", "", "CA file to verify client certificates, enable mutual TLS.")
+}
+
+func addClientTLSFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&tlsEnable, "tls", false, "Use TLS for tcp, wss for ws")
+	cmd.Flags().StringVar(&tlsCert, "cert", "", "Client certificate file for mutual TLS")
+	cmd.Flags().StringVar(&tlsKey, "key", "", "Client private key file")
+	cmd.Flags().StringVar(&tlsCA, "ca", "", "CA file to verify server certificate, default to system roots")
+}
@@ -0,0 +1,97 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/TechCatsLab/redalert/transport"
+)
+
+var (
+	socketPath string
+	wsPath     string
+	wsAddr     string
+)
+
+var errNoSocket = errors.New("--socket is required by unix transports")
+
+// addSocketFlag add flag of unix socket path to cmd
+func addSocketFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&socketPath, "socket", "", "Path of socket of unix and unixgram transports, such as /run/redalert.sock")
+}
+
+// addWSPathFlag add flag of WebSocket path to cmd of a client
+func addWSPathFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&wsPath, "ws-path", "/redalert", "Path of WebSocket on server of ws transport")
+}
+
+// isStream tell if transport name runs the tcp protocol on a stream
+func isStream(name string) bool {
+	return name == transport.TCP || name == transport.Unix || name == transport.WS
+}
+
+// isSocket tell if transport name is addressed by a socket path
+func isSocket(name string) bool {
+	return name == transport.Unix || name == transport.Unixgram
+}
+
+// addrOf return address of transport name, the socket path for unix
+// transports, host:port/path for ws and host:port for others
+func addrOf(name, host, port string) (string, error) {
+	if name == transport.WS {
+		return net.JoinHostPort(host, port) + "/" + strings.TrimPrefix(wsPath, "/"), nil
+	}
+
+	if !isSocket(name) {
+		return net.JoinHostPort(host, port), nil
+	}
+
+	if socketPath == "" {
+		return "", errNoSocket
+	}
+
+	return socketPath, nil
+}
+
+// transportOf return transport registered as name
+func transportOf(name string) (transport.Transport, error) {
+	t := transport.Get(name)
+	if t == nil {
+		return nil, fmt.Errorf("Unknown transport %q, one of %s", name, strings.Join(transport.Names(), ", "))
+	}
+
+	return t, nil
+}
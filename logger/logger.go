@@ -0,0 +1,143 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package logger
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"sync/atomic"
+)
+
+// ErrFormat error for log format is neither text nor json
+var ErrFormat = errors.New("Log format must be text or json")
+
+// Logger logs messages of levels, args are fields of message as key-value
+// pairs such as "file", name
+type Logger interface {
+	Debug(msg string, args ...any)
+	Info(msg string, args ...any)
+	Warn(msg string, args ...any)
+	Error(msg string, args ...any)
+
+	// With return a logger adding args to every message, such as fields of
+	// a session
+	With(args ...any) Logger
+}
+
+// slogLogger is a Logger by log/slog
+type slogLogger struct {
+	l *slog.Logger
+}
+
+var (
+	// Discard logs nothing
+	Discard Logger = slogLogger{slog.New(slog.DiscardHandler)}
+
+	defaultLogger atomic.Value
+)
+
+func init() {
+	defaultLogger.Store(holder{FromSlog(slog.New(slog.NewTextHandler(os.Stderr, nil)))})
+}
+
+// holder keeps loggers of different types in defaultLogger
+type holder struct {
+	Logger
+}
+
+// FromSlog return a Logger writing to l
+func FromSlog(l *slog.Logger) Logger {
+	return slogLogger{l}
+}
+
+// New return a Logger writing messages of level and above to w, format is
+// text or json
+func New(w io.Writer, level slog.Level, format string) (Logger, error) {
+	opts := &slog.HandlerOptions{Level: level}
+
+	switch format {
+	case "", "text":
+		return FromSlog(slog.New(slog.NewTextHandler(w, opts))), nil
+	case "json":
+		return FromSlog(slog.New(slog.NewJSONHandler(w, opts))), nil
+	}
+
+	return nil, ErrFormat
+}
+
+// ParseLevel parse level such as debug, info, warn or error
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	err := level.UnmarshalText([]byte(s))
+
+	return level, err
+}
+
+// Default return the default logger, it writes text of info and above to
+// stderr unless SetDefault is called
+func Default() Logger {
+	return defaultLogger.Load().(holder).Logger
+}
+
+// SetDefault make l the default logger
+func SetDefault(l Logger) {
+	defaultLogger.Store(holder{l})
+}
+
+// Or return l, or the default logger if l is nil
+func Or(l Logger) Logger {
+	if l == nil {
+		return Default()
+	}
+
+	return l
+}
+
+func (s slogLogger) Debug(msg string, args ...any) {
+	s.l.Debug(msg, args...)
+}
+
+func (s slogLogger) Info(msg string, args ...any) {
+	s.l.Info(msg, args...)
+}
+
+func (s slogLogger) Warn(msg string, args ...any) {
+	s.l.Warn(msg, args...)
+}
+
+func (s slogLogger) Error(msg string, args ...any) {
+	s.l.Error(msg, args...)
+}
+
+func (s slogLogger) With(args ...any) Logger {
+	return slogLogger{s.l.With(args...)}
+}
@@ -0,0 +1,178 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package memnet
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// ErrAddrInUse address is bound already
+	ErrAddrInUse = errors.New("Address already in use")
+
+	// ErrRefused nothing listens on address
+	ErrRefused = errors.New("Connection refused")
+
+	// ErrNotDialed write a packet conn not dialed without an address
+	ErrNotDialed = errors.New("Packet conn not dialed")
+)
+
+// Link is how a network delivers data. Packets are lost, duplicated,
+// reordered and corrupted by chance, streams are reliable like TCP and only
+// take latency, jitter and bandwidth.
+type Link struct {
+	Loss      float64       // Chance of a packet lost
+	Duplicate float64       // Chance of a packet delivered twice
+	Reorder   float64       // Chance of a packet delayed behind later ones
+	Corrupt   float64       // Chance of a byte of packet flipped
+	Latency   time.Duration // Delay of delivery
+	Jitter    time.Duration // Random delay up to it added to Latency
+	Bandwidth float64       // Bytes per second of a sender, no limit if 0
+	Seed      int64         // Seed of chances, the same seed repeats a run
+}
+
+// Addr is an address of a Network
+type Addr string
+
+// Network is "mem"
+func (a Addr) Network() string {
+	return "mem"
+}
+
+func (a Addr) String() string {
+	return string(a)
+}
+
+// Network is an in-process network of packet and stream conns bound to
+// addresses, connected by a Link
+type Network struct {
+	link Link
+
+	mu        sync.Mutex
+	rand      *rand.Rand
+	packets   map[Addr]*PacketConn
+	listeners map[Addr]*Listener
+}
+
+// seq numbers addresses of dialing conns, they're unique in the process as
+// servers may key clients by address in tables shared by networks
+var seq uint64
+
+// New create a Network of link
+func New(link Link) *Network {
+	return &Network{
+		link:      link,
+		rand:      rand.New(rand.NewSource(link.Seed)),
+		packets:   make(map[Addr]*PacketConn),
+		listeners: make(map[Addr]*Listener),
+	}
+}
+
+// ephemeral return a new address to bind a dialing conn
+func ephemeral() Addr {
+	return Addr(fmt.Sprintf("mem:%d", atomic.AddUint64(&seq, 1)))
+}
+
+// chance tell if an event of chance p happens
+func (n *Network) chance(p float64) bool {
+	if p <= 0 {
+		return false
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return n.rand.Float64() < p
+}
+
+// delay return latency of a delivery with jitter
+func (n *Network) delay() time.Duration {
+	if n.link.Jitter <= 0 {
+		return n.link.Latency
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return n.link.Latency + time.Duration(n.rand.Int63n(int64(n.link.Jitter)))
+}
+
+// corrupt flip a random byte of data
+func (n *Network) corrupt(data []byte) {
+	if len(data) == 0 {
+		return
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	data[n.rand.Intn(len(data))] ^= byte(1 + n.rand.Intn(255))
+}
+
+// wire is the sending side of a conn on the link
+type wire struct {
+	net *Network
+
+	mu   sync.Mutex
+	busy time.Time // when the sender is done with data sent
+	last time.Time // arrival of the last chunk of a stream
+}
+
+// transmit return when data of size sent now leaves the sender, and when it
+// arrives, a stream arrives in order
+func (w *wire) transmit(size int, stream bool) (time.Time, time.Time) {
+	now := time.Now()
+	delay := w.net.delay()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.busy.Before(now) {
+		w.busy = now
+	}
+	if rate := w.net.link.Bandwidth; rate > 0 {
+		w.busy = w.busy.Add(time.Duration(float64(size) / rate * float64(time.Second)))
+	}
+
+	at := w.busy.Add(delay)
+	if stream {
+		if at.Before(w.last) {
+			at = w.last
+		}
+		w.last = at
+	}
+
+	return w.busy, at
+}
@@ -0,0 +1,186 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package memnet
+
+import (
+	"net"
+	"time"
+)
+
+// PacketConn is a packet conn of a Network like a UDP socket, it's also a
+// net.Conn if it's dialed
+type PacketConn struct {
+	net    *Network
+	addr   Addr
+	remote net.Addr // the only peer if dialed
+	queue  *queue
+	wire   *wire
+}
+
+// ListenPacket bind a packet conn to addr
+func (n *Network) ListenPacket(addr string) (*PacketConn, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if _, ok := n.packets[Addr(addr)]; ok {
+		return nil, ErrAddrInUse
+	}
+
+	return n.bind(Addr(addr)), nil
+}
+
+// DialPacket bind a packet conn to a new address and connect it to addr,
+// packets to addr are lost if nothing is bound to it, like UDP
+func (n *Network) DialPacket(addr string) (*PacketConn, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	c := n.bind(ephemeral())
+	c.remote = Addr(addr)
+
+	return c, nil
+}
+
+// bind make a packet conn of addr, n.mu is held
+func (n *Network) bind(addr Addr) *PacketConn {
+	c := &PacketConn{
+		net:   n,
+		addr:  addr,
+		queue: newQueue(),
+		wire:  &wire{net: n},
+	}
+	n.packets[addr] = c
+
+	return c
+}
+
+// ReadFrom read a packet and return where it's from
+func (c *PacketConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	return c.queue.pop(b, false)
+}
+
+// WriteTo send b to addr through the link, it's lost silently if nothing
+// is bound to addr
+func (c *PacketConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	c.queue.mu.Lock()
+	closed := c.queue.closed
+	c.queue.mu.Unlock()
+	if closed {
+		return 0, net.ErrClosed
+	}
+
+	n := c.net
+	_, at := c.wire.transmit(len(b), false)
+
+	if n.chance(n.link.Loss) {
+		return len(b), nil
+	}
+
+	n.mu.Lock()
+	dst, ok := n.packets[Addr(addr.String())]
+	n.mu.Unlock()
+	if !ok {
+		return len(b), nil
+	}
+
+	data := append([]byte(nil), b...)
+	if n.chance(n.link.Corrupt) {
+		n.corrupt(data)
+	}
+
+	if n.chance(n.link.Reorder) {
+		at = at.Add(n.link.Latency + n.delay() + time.Millisecond)
+	}
+	dst.queue.push(item{data: data, from: c.addr, at: at})
+
+	if n.chance(n.link.Duplicate) {
+		dst.queue.push(item{data: data, from: c.addr, at: at.Add(n.delay())})
+	}
+
+	return len(b), nil
+}
+
+// Read read a packet from the peer of a dialed conn
+func (c *PacketConn) Read(b []byte) (int, error) {
+	for {
+		n, from, err := c.ReadFrom(b)
+		if err != nil || c.remote == nil || from.String() == c.remote.String() {
+			return n, err
+		}
+	}
+}
+
+// Write send b to the peer of a dialed conn
+func (c *PacketConn) Write(b []byte) (int, error) {
+	if c.remote == nil {
+		return 0, ErrNotDialed
+	}
+
+	return c.WriteTo(b, c.remote)
+}
+
+// Close unbind conn and make readers return net.ErrClosed
+func (c *PacketConn) Close() error {
+	c.net.mu.Lock()
+	if c.net.packets[c.addr] == c {
+		delete(c.net.packets, c.addr)
+	}
+	c.net.mu.Unlock()
+
+	c.queue.close()
+
+	return nil
+}
+
+// LocalAddr return address conn is bound to
+func (c *PacketConn) LocalAddr() net.Addr {
+	return c.addr
+}
+
+// RemoteAddr return the peer of a dialed conn, nil if it's not dialed
+func (c *PacketConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+// SetDeadline set read deadline, writes never block
+func (c *PacketConn) SetDeadline(t time.Time) error {
+	return c.SetReadDeadline(t)
+}
+
+// SetReadDeadline make reads return an error once t passes
+func (c *PacketConn) SetReadDeadline(t time.Time) error {
+	c.queue.setDeadline(t)
+	return nil
+}
+
+// SetWriteDeadline do nothing, writes never block
+func (c *PacketConn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
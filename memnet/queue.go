@@ -0,0 +1,176 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package memnet
+
+import (
+	"io"
+	"net"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// item is data in flight to a conn
+type item struct {
+	data []byte
+	from net.Addr
+	at   time.Time // arrival
+}
+
+// queue is data in flight to a conn in order of arrival, readers wait for
+// the first item to arrive
+type queue struct {
+	mu       sync.Mutex
+	items    []item
+	wake     chan struct{} // closed once items or state changed
+	closed   bool          // conn is closed
+	shut     bool          // no more data, reads get io.EOF after items
+	deadline time.Time
+}
+
+func newQueue() *queue {
+	return &queue{wake: make(chan struct{})}
+}
+
+// notify wake readers waiting, q.mu is held
+func (q *queue) notify() {
+	close(q.wake)
+	q.wake = make(chan struct{})
+}
+
+// push add it, it returns false if queue takes no more data
+func (q *queue) push(it item) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed || q.shut {
+		return false
+	}
+
+	i := sort.Search(len(q.items), func(i int) bool {
+		return q.items[i].at.After(it.at)
+	})
+	q.items = append(q.items, item{})
+	copy(q.items[i+1:], q.items[i:])
+	q.items[i] = it
+	q.notify()
+
+	return true
+}
+
+// pop wait for the first item to arrive and read it into b. A stream reads
+// a part of item if b is short and keeps the rest, a packet is truncated.
+func (q *queue) pop(b []byte, stream bool) (int, net.Addr, error) {
+	for {
+		q.mu.Lock()
+		if q.closed {
+			q.mu.Unlock()
+			return 0, nil, net.ErrClosed
+		}
+
+		now := time.Now()
+		if !q.deadline.IsZero() && !now.Before(q.deadline) {
+			q.mu.Unlock()
+			return 0, nil, os.ErrDeadlineExceeded
+		}
+
+		if len(q.items) > 0 && !q.items[0].at.After(now) {
+			it := &q.items[0]
+			n := copy(b, it.data)
+			from := it.from
+			if stream && n < len(it.data) {
+				it.data = it.data[n:]
+			} else {
+				q.items = q.items[1:]
+			}
+			q.mu.Unlock()
+
+			return n, from, nil
+		}
+
+		if len(q.items) == 0 && q.shut {
+			q.mu.Unlock()
+			return 0, nil, io.EOF
+		}
+
+		wait := time.Duration(-1)
+		if len(q.items) > 0 {
+			wait = q.items[0].at.Sub(now)
+		}
+		if !q.deadline.IsZero() {
+			if d := q.deadline.Sub(now); wait < 0 || d < wait {
+				wait = d
+			}
+		}
+		wake := q.wake
+		q.mu.Unlock()
+
+		if wait < 0 {
+			<-wake
+			continue
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-wake:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+// setDeadline make readers return once t passes, no deadline if t is zero
+func (q *queue) setDeadline(t time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.deadline = t
+	q.notify()
+}
+
+// close drop items and make readers return net.ErrClosed
+func (q *queue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.closed = true
+	q.items = nil
+	q.notify()
+}
+
+// closeWrite take no more data, readers get io.EOF once items are read
+func (q *queue) closeWrite() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.shut = true
+	q.notify()
+}
@@ -0,0 +1,188 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package memnet
+
+import (
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// Listener accepts stream conns of a Network like a TCP listener
+type Listener struct {
+	net   *Network
+	addr  Addr
+	conns chan *Conn
+	done  chan struct{}
+	once  sync.Once
+}
+
+// Conn is a stream conn of a Network like a TCP conn, data is never lost,
+// duplicated, reordered or corrupted
+type Conn struct {
+	local  Addr
+	remote Addr
+	in     *queue // data to read
+	out    *queue // data to the peer
+	wire   *wire
+}
+
+// Listen accept stream conns to addr
+func (n *Network) Listen(addr string) (*Listener, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if _, ok := n.listeners[Addr(addr)]; ok {
+		return nil, ErrAddrInUse
+	}
+
+	l := &Listener{
+		net:   n,
+		addr:  Addr(addr),
+		conns: make(chan *Conn),
+		done:  make(chan struct{}),
+	}
+	n.listeners[l.addr] = l
+
+	return l, nil
+}
+
+// Dial connect a stream conn to the listener of addr
+func (n *Network) Dial(addr string) (*Conn, error) {
+	n.mu.Lock()
+	l, ok := n.listeners[Addr(addr)]
+	local := ephemeral()
+	n.mu.Unlock()
+	if !ok {
+		return nil, ErrRefused
+	}
+
+	c2s, s2c := newQueue(), newQueue()
+	client := &Conn{local: local, remote: l.addr, in: s2c, out: c2s, wire: &wire{net: n}}
+	server := &Conn{local: l.addr, remote: local, in: c2s, out: s2c, wire: &wire{net: n}}
+
+	select {
+	case l.conns <- server:
+		return client, nil
+	case <-l.done:
+		return nil, ErrRefused
+	}
+}
+
+// Accept wait for a conn dialed
+func (l *Listener) Accept() (net.Conn, error) {
+	select {
+	case c := <-l.conns:
+		return c, nil
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+// Close stop listening, Accept returns net.ErrClosed
+func (l *Listener) Close() error {
+	l.once.Do(func() {
+		l.net.mu.Lock()
+		delete(l.net.listeners, l.addr)
+		l.net.mu.Unlock()
+
+		close(l.done)
+	})
+
+	return nil
+}
+
+// Addr return address of listener
+func (l *Listener) Addr() net.Addr {
+	return l.addr
+}
+
+// Read read data sent by the peer, io.EOF once the peer closed
+func (c *Conn) Read(b []byte) (int, error) {
+	n, _, err := c.in.pop(b, true)
+	return n, err
+}
+
+// Write send b to the peer, it blocks until b is sent at Bandwidth
+func (c *Conn) Write(b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+
+	sent, at := c.wire.transmit(len(b), true)
+	if !c.out.push(item{data: append([]byte(nil), b...), from: c.local, at: at}) {
+		return 0, io.ErrClosedPipe
+	}
+
+	time.Sleep(time.Until(sent))
+
+	return len(b), nil
+}
+
+// Close close both ways, the peer reads io.EOF
+func (c *Conn) Close() error {
+	c.in.close()
+	c.out.closeWrite()
+
+	return nil
+}
+
+// CloseWrite close the way to the peer, the peer reads io.EOF
+func (c *Conn) CloseWrite() error {
+	c.out.closeWrite()
+	return nil
+}
+
+// LocalAddr return address of conn
+func (c *Conn) LocalAddr() net.Addr {
+	return c.local
+}
+
+// RemoteAddr return address of the peer
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+// SetDeadline set read deadline, writes only block by Bandwidth
+func (c *Conn) SetDeadline(t time.Time) error {
+	return c.SetReadDeadline(t)
+}
+
+// SetReadDeadline make reads return an error once t passes
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	c.in.setDeadline(t)
+	return nil
+}
+
+// SetWriteDeadline do nothing, writes only block by Bandwidth
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
@@ -0,0 +1,342 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package metrics
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// contentType is the Prometheus text exposition format
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Registry holds families of metrics by name, it's an http.Handler serving
+// them in Prometheus text exposition format
+type Registry struct {
+	mu       sync.Mutex
+	families map[string]*family
+}
+
+var (
+	// Default is the registry of metrics of redalert
+	Default = NewRegistry()
+
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+// family is a metric of name and its children by values of labels
+type family struct {
+	name    string
+	help    string
+	kind    string
+	labels  []string
+	buckets []float64 // upper bounds of a histogram
+
+	mu       sync.Mutex
+	children map[string]child
+}
+
+// child is a metric with values of labels of its family
+type child interface {
+	write(w *bytes.Buffer, name, labels string)
+}
+
+// Counter is a value only goes up
+type Counter struct {
+	bits uint64
+}
+
+// Gauge is a value goes up and down
+type Gauge struct {
+	bits uint64
+}
+
+// Histogram counts observations in buckets
+type Histogram struct {
+	upper []float64
+
+	mu     sync.Mutex
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// CounterVec is counters by values of labels
+type CounterVec struct {
+	f *family
+}
+
+// GaugeVec is gauges by values of labels
+type GaugeVec struct {
+	f *family
+}
+
+// HistogramVec is histograms by values of labels
+type HistogramVec struct {
+	f *family
+}
+
+// NewRegistry create an empty registry
+func NewRegistry() *Registry {
+	return &Registry{
+		families: make(map[string]*family),
+	}
+}
+
+// register add family of name, a name registered twice panics
+func (r *Registry) register(f *family) *family {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.families[f.name]; ok {
+		panic("metrics: " + f.name + " registered twice")
+	}
+
+	f.children = make(map[string]child)
+	r.families[f.name] = f
+
+	return f
+}
+
+// NewCounterVec register counters of name by labels
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{r.register(&family{name: name, help: help, kind: "counter", labels: labels})}
+}
+
+// NewGaugeVec register gauges of name by labels
+func (r *Registry) NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	return &GaugeVec{r.register(&family{name: name, help: help, kind: "gauge", labels: labels})}
+}
+
+// NewHistogramVec register histograms of name by labels, buckets are upper
+// bounds in increasing order, +Inf is implied
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	return &HistogramVec{r.register(&family{name: name, help: help, kind: "histogram", labels: labels, buckets: buckets})}
+}
+
+// With return counter of values of labels
+func (v *CounterVec) With(values ...string) *Counter {
+	return v.f.get(values, func() child { return &Counter{} }).(*Counter)
+}
+
+// With return gauge of values of labels
+func (v *GaugeVec) With(values ...string) *Gauge {
+	return v.f.get(values, func() child { return &Gauge{} }).(*Gauge)
+}
+
+// With return histogram of values of labels
+func (v *HistogramVec) With(values ...string) *Histogram {
+	return v.f.get(values, func() child {
+		return &Histogram{upper: v.f.buckets, counts: make([]uint64, len(v.f.buckets))}
+	}).(*Histogram)
+}
+
+// get return child of values, it's created by fn at first, values must
+// match labels of f
+func (f *family) get(values []string, fn func() child) child {
+	if len(values) != len(f.labels) {
+		panic("metrics: " + f.name + " wants labels " + strings.Join(f.labels, ","))
+	}
+
+	pairs := make([]string, len(values))
+	for i, v := range values {
+		pairs[i] = f.labels[i] + `="` + labelEscaper.Replace(v) + `"`
+	}
+	key := strings.Join(pairs, ",")
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, ok := f.children[key]
+	if !ok {
+		c = fn()
+		f.children[key] = c
+	}
+
+	return c
+}
+
+// Add add v to counter, v must not be negative
+func (c *Counter) Add(v float64) {
+	addFloat(&c.bits, v)
+}
+
+// Inc add 1 to counter
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// Value return value of counter
+func (c *Counter) Value() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&c.bits))
+}
+
+func (c *Counter) write(w *bytes.Buffer, name, labels string) {
+	writeSample(w, name, labels, c.Value())
+}
+
+// Add add v to gauge, it may be negative
+func (g *Gauge) Add(v float64) {
+	addFloat(&g.bits, v)
+}
+
+// Inc add 1 to gauge
+func (g *Gauge) Inc() {
+	g.Add(1)
+}
+
+// Dec subtract 1 from gauge
+func (g *Gauge) Dec() {
+	g.Add(-1)
+}
+
+// Set set gauge to v
+func (g *Gauge) Set(v float64) {
+	atomic.StoreUint64(&g.bits, math.Float64bits(v))
+}
+
+// Value return value of gauge
+func (g *Gauge) Value() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&g.bits))
+}
+
+func (g *Gauge) write(w *bytes.Buffer, name, labels string) {
+	writeSample(w, name, labels, g.Value())
+}
+
+// Observe count v in the first bucket it fits
+func (h *Histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(h.upper, v)
+
+	h.mu.Lock()
+	if i < len(h.counts) {
+		h.counts[i]++
+	}
+	h.sum += v
+	h.count++
+	h.mu.Unlock()
+}
+
+func (h *Histogram) write(w *bytes.Buffer, name, labels string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	prefix := labels
+	if prefix != "" {
+		prefix += ","
+	}
+
+	var cumulative uint64
+	for i, upper := range h.upper {
+		cumulative += h.counts[i]
+		writeSample(w, name+"_bucket", prefix+`le="`+formatFloat(upper)+`"`, float64(cumulative))
+	}
+	writeSample(w, name+"_bucket", prefix+`le="+Inf"`, float64(h.count))
+	writeSample(w, name+"_sum", labels, h.sum)
+	writeSample(w, name+"_count", labels, float64(h.count))
+}
+
+// addFloat add v to the float64 in bits atomically
+func addFloat(bits *uint64, v float64) {
+	for {
+		old := atomic.LoadUint64(bits)
+		sum := math.Float64bits(math.Float64frombits(old) + v)
+		if atomic.CompareAndSwapUint64(bits, old, sum) {
+			return
+		}
+	}
+}
+
+func writeSample(w *bytes.Buffer, name, labels string, v float64) {
+	w.WriteString(name)
+	if labels != "" {
+		w.WriteString("{" + labels + "}")
+	}
+	w.WriteString(" " + formatFloat(v) + "\n")
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// WriteTo write all metrics in text exposition format, families are sorted
+// by name and children by labels
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	families := make([]*family, 0, len(r.families))
+	for _, f := range r.families {
+		families = append(families, f)
+	}
+	r.mu.Unlock()
+
+	sort.Slice(families, func(i, j int) bool { return families[i].name < families[j].name })
+
+	var buf bytes.Buffer
+	for _, f := range families {
+		f.mu.Lock()
+		keys := make([]string, 0, len(f.children))
+		for k := range f.children {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		if len(keys) > 0 {
+			buf.WriteString("# HELP " + f.name + " " + helpEscaper.Replace(f.help) + "\n")
+			buf.WriteString("# TYPE " + f.name + " " + f.kind + "\n")
+		}
+		for _, k := range keys {
+			f.children[k].write(&buf, f.name, k)
+		}
+		f.mu.Unlock()
+	}
+
+	return buf.WriteTo(w)
+}
+
+// ServeHTTP write all metrics
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", contentType)
+	r.WriteTo(w)
+}
@@ -0,0 +1,147 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package metrics
+
+import (
+	"sync"
+	"time"
+)
+
+// Directions of a transfer
+const (
+	Receive = "receive"
+	Send    = "send"
+)
+
+// Results of a finished transfer, it's ok or the reason of failure
+const (
+	ResultOK           = "ok"
+	ResultHashMismatch = "hash_mismatch"
+	ResultTimeout      = "timeout"
+	ResultDenied       = "denied"
+	ResultUnauthorized = "unauthorized"
+	ResultError        = "error"
+)
+
+// Metrics of transfers, transport is the network of peer such as tcp, udp,
+// unix, unixgram or http
+var (
+	ReceivedBytes  = Default.NewCounterVec("redalert_received_bytes_total", "Bytes of files received.", "transport")
+	SentBytes      = Default.NewCounterVec("redalert_sent_bytes_total", "Bytes of files sent.", "transport")
+	ActiveSessions = Default.NewGaugeVec("redalert_active_sessions", "Transfers in progress.", "transport", "direction")
+	Retransmits    = Default.NewCounterVec("redalert_retransmitted_packets_total", "Packets sent again for no reply in time.", "transport")
+	Duplicates     = Default.NewCounterVec("redalert_duplicate_packets_total", "Packets received more than once.", "transport")
+	HashMismatches = Default.NewCounterVec("redalert_hash_mismatches_total", "Files received not matching hash of the sender.", "transport")
+	Timeouts       = Default.NewCounterVec("redalert_session_timeouts_total", "Sessions closed for the peer being quiet too long.", "transport")
+	Transfers      = Default.NewCounterVec("redalert_transfers_total", "Finished transfers by result, ok or reason of failure.", "transport", "direction", "result")
+	Durations      = Default.NewHistogramVec("redalert_transfer_duration_seconds", "Duration of finished transfers.", durationBuckets, "transport", "direction", "result")
+	Sizes          = Default.NewHistogramVec("redalert_transfer_size_bytes", "Size of files transferred successfully.", sizeBuckets, "transport", "direction")
+)
+
+var (
+	durationBuckets = []float64{.01, .05, .1, .5, 1, 5, 10, 30, 60, 300, 1800}
+	sizeBuckets     = []float64{1 << 10, 1 << 14, 1 << 17, 1 << 20, 1 << 24, 1 << 27, 1 << 30, 1 << 34}
+)
+
+// Transfer tracks a file in transfer, it's an active session until done. A
+// nil Transfer tracks nothing.
+type Transfer struct {
+	transport string
+	direction string
+	begin     time.Time
+	bytes     *Counter
+	size      int64
+	once      sync.Once
+}
+
+// Start track a transfer of direction over transport
+func Start(transport, direction string) *Transfer {
+	t := &Transfer{
+		transport: transport,
+		direction: direction,
+		begin:     time.Now(),
+	}
+	if direction == Send {
+		t.bytes = SentBytes.With(transport)
+	} else {
+		t.bytes = ReceivedBytes.With(transport)
+	}
+
+	ActiveSessions.With(transport, direction).Inc()
+
+	return t
+}
+
+// Add count n bytes of file transferred
+func (t *Transfer) Add(n int) {
+	if t == nil || n <= 0 {
+		return
+	}
+
+	t.size += int64(n)
+	t.bytes.Add(float64(n))
+}
+
+// Write count bytes of p, so content of file can be teed to t
+func (t *Transfer) Write(p []byte) (int, error) {
+	t.Add(len(p))
+
+	return len(p), nil
+}
+
+// Done finish the transfer with result, only the first call counts
+func (t *Transfer) Done(result string) {
+	if t == nil {
+		return
+	}
+
+	t.once.Do(func() {
+		ActiveSessions.With(t.transport, t.direction).Dec()
+		Reject(t.transport, t.direction, result)
+		Durations.With(t.transport, t.direction, result).Observe(time.Since(t.begin).Seconds())
+
+		if result == ResultOK {
+			Sizes.With(t.transport, t.direction).Observe(float64(t.size))
+		}
+	})
+}
+
+// Reject count a request rejected before its transfer starts, hash
+// mismatches and timeouts are counted by result too
+func Reject(transport, direction, result string) {
+	Transfers.With(transport, direction, result).Inc()
+
+	switch result {
+	case ResultHashMismatch:
+		HashMismatches.With(transport).Inc()
+	case ResultTimeout:
+		Timeouts.With(transport).Inc()
+	}
+}
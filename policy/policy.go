@@ -0,0 +1,187 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package policy
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path"
+	"strings"
+)
+
+// Op is an operation of client
+type Op int
+
+const (
+	// Read download a file from server
+	Read Op = iota
+	// Write upload a file to server
+	Write
+	// Relay send a file through server to its next hop
+	Relay
+)
+
+var (
+	// ErrNoRead error for client may not download
+	ErrNoRead = errors.New("Read not permitted")
+	// ErrNoWrite error for client may not upload
+	ErrNoWrite = errors.New("Write not permitted")
+	// ErrNoRelay error for client may not send through relay
+	ErrNoRelay = errors.New("Relay not permitted")
+	// ErrDir error for file not in allowed dirs
+	ErrDir = errors.New("Dir not permitted")
+	// ErrExtension error for extension of file not allowed
+	ErrExtension = errors.New("Extension not permitted")
+	// ErrTooLarge error for file exceeds max size
+	ErrTooLarge = errors.New("File too large")
+)
+
+// Rule is what a client may do
+type Rule struct {
+	Read       bool     `json:"read"`       // May download
+	Write      bool     `json:"write"`      // May upload
+	Dirs       []string `json:"dirs"`       // Allowed subdirectories, any if empty
+	Extensions []string `json:"extensions"` // Allowed extensions such as ".log", any if empty
+	MaxSize    int64    `json:"max_size"`   // Max size of uploaded file in bytes, no limit if 0
+	Relay      bool     `json:"relay"`      // May send through a relay to its next hops
+}
+
+// Policy holds rules of clients by ID, Default applies to clients without
+// own rule, and to all clients when authentication is disabled. A client
+// without rule may do nothing.
+type Policy struct {
+	Default *Rule            `json:"default"`
+	Clients map[string]*Rule `json:"clients"`
+}
+
+// Load read policy from JSON file
+func Load(file string) (*Policy, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Policy{}
+	if err = json.Unmarshal(data, p); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// Denied report if err is returned by Check
+func Denied(err error) bool {
+	switch err {
+	case ErrNoRead, ErrNoWrite, ErrNoRelay, ErrDir, ErrExtension, ErrTooLarge:
+		return true
+	}
+
+	return false
+}
+
+// Rule return rule of client, nil if client may do nothing
+func (p *Policy) Rule(client string) *Rule {
+	if rule, ok := p.Clients[client]; ok {
+		return rule
+	}
+
+	return p.Default
+}
+
+// Check if client may op on file name of size, size is ignored if it's
+// unknown(negative). A nil policy permits everything.
+func (p *Policy) Check(client string, op Op, name string, size int64) error {
+	if p == nil {
+		return nil
+	}
+
+	rule := p.Rule(client)
+
+	switch {
+	case op == Read && (rule == nil || !rule.Read):
+		return ErrNoRead
+	case op == Write && (rule == nil || !rule.Write):
+		return ErrNoWrite
+	case op == Relay && (rule == nil || !rule.Relay):
+		return ErrNoRelay
+	}
+
+	name = path.Clean("/" + name)
+
+	if len(rule.Dirs) > 0 && !inDirs(name, rule.Dirs) {
+		return ErrDir
+	}
+
+	if len(rule.Extensions) > 0 && !hasExtension(name, rule.Extensions) {
+		return ErrExtension
+	}
+
+	if op != Read && size >= 0 && rule.MaxSize > 0 && size > rule.MaxSize {
+		return ErrTooLarge
+	}
+
+	return nil
+}
+
+// MaxSize return max size of file client may upload, 0 for no limit
+func (p *Policy) MaxSize(client string) int64 {
+	if p == nil {
+		return 0
+	}
+
+	if rule := p.Rule(client); rule != nil {
+		return rule.MaxSize
+	}
+
+	return 0
+}
+
+func inDirs(name string, dirs []string) bool {
+	for _, dir := range dirs {
+		dir = path.Clean("/" + dir)
+		if dir == "/" || strings.HasPrefix(name, dir+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hasExtension(name string, extensions []string) bool {
+	ext := path.Ext(name)
+	for _, e := range extensions {
+		if strings.EqualFold(ext, e) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,124 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package policy_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/TechCatsLab/redalert/policy"
+)
+
+const policyJSON = `{
+	"default": {"read": true},
+	"clients": {
+		"alice": {"read": true, "write": true, "dirs": ["logs", "/tmp/"], "extensions": [".log", ".GZ"], "max_size": 1000},
+		"relay": {"write": true, "relay": true},
+		"nobody": null
+	}
+}`
+
+func load(t *testing.T) *policy.Policy {
+	file := filepath.Join(t.TempDir(), "policy.json")
+	if err := os.WriteFile(file, []byte(policyJSON), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := policy.Load(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return p
+}
+
+func TestCheck(t *testing.T) {
+	p := load(t)
+
+	cases := []struct {
+		name   string
+		client string
+		op     policy.Op
+		file   string
+		size   int64
+		err    error
+	}{
+		{"default read", "", policy.Read, "a.bin", -1, nil},
+		{"default write", "", policy.Write, "a.bin", 10, policy.ErrNoWrite},
+		{"unknown client gets default", "carol", policy.Read, "a.bin", -1, nil},
+		{"client without rule", "nobody", policy.Read, "a.bin", -1, policy.ErrNoRead},
+		{"write in dir", "alice", policy.Write, "logs/a.log", 10, nil},
+		{"write in nested dir", "alice", policy.Write, "tmp/x/a.gz", 10, nil},
+		{"write out of dirs", "alice", policy.Write, "etc/a.log", 10, policy.ErrDir},
+		{"dir prefix isn't a dir", "alice", policy.Write, "logsx/a.log", 10, policy.ErrDir},
+		{"escape by dot dot", "alice", policy.Write, "logs/../etc/a.log", 10, policy.ErrDir},
+		{"extension not allowed", "alice", policy.Write, "logs/a.txt", 10, policy.ErrExtension},
+		{"extension case", "alice", policy.Write, "logs/a.LOG", 10, nil},
+		{"too large", "alice", policy.Write, "logs/a.log", 1001, policy.ErrTooLarge},
+		{"size unknown", "alice", policy.Write, "logs/a.log", -1, nil},
+		{"read ignores size", "alice", policy.Read, "logs/a.log", 5000, nil},
+		{"relay allowed", "relay", policy.Relay, "a.bin", 10, nil},
+		{"relay not allowed", "alice", policy.Relay, "logs/a.log", 10, policy.ErrNoRelay},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := p.Check(c.client, c.op, c.file, c.size)
+			if err != c.err {
+				t.Fatalf("got %v, want %v", err, c.err)
+			}
+			if err != nil && !policy.Denied(err) {
+				t.Fatalf("%v isn't a denial", err)
+			}
+		})
+	}
+}
+
+func TestNilPolicy(t *testing.T) {
+	var p *policy.Policy
+
+	if err := p.Check("", policy.Write, "/etc/passwd", 1<<40); err != nil {
+		t.Fatalf("nil policy denies: %v", err)
+	}
+	if p.MaxSize("alice") != 0 {
+		t.Fatal("nil policy limits size")
+	}
+}
+
+func TestMaxSize(t *testing.T) {
+	p := load(t)
+
+	for client, want := range map[string]int64{"alice": 1000, "relay": 0, "nobody": 0, "carol": 0} {
+		if got := p.MaxSize(client); got != want {
+			t.Errorf("max size of %q is %d, want %d", client, got, want)
+		}
+	}
+}
@@ -32,8 +32,13 @@ package protocol
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
+	"io"
 )
 
+// ErrPackSize error for pack size in header larger than buffer
+var ErrPackSize = errors.New("Pack size out of range")
+
 type Encode struct {
 	Body   []byte
 	Buffer *bytes.Buffer
@@ -56,3 +61,25 @@ func (e *Encode) Unmarshal(proto *Proto) {
 	binary.Read(e.Buffer, binary.BigEndian, &proto.PackSize)
 	binary.Read(e.Buffer, binary.BigEndian, &proto.PackOrder)
 }
+
+// ReadPacket read a whole packet from stream r into e.Body and unmarshal its
+// header to proto. PackSize of header is the size of packet body.
+func ReadPacket(r io.Reader, e *Encode, proto *Proto) (int, error) {
+	if _, err := io.ReadFull(r, e.Body[:FixedHeaderSize]); err != nil {
+		return 0, err
+	}
+
+	e.Buffer = bytes.NewBuffer(e.Body)
+	e.Unmarshal(proto)
+
+	size := FixedHeaderSize + int(proto.PackSize)
+	if size > len(e.Body) {
+		return 0, ErrPackSize
+	}
+
+	if _, err := io.ReadFull(r, e.Body[FixedHeaderSize:size]); err != nil {
+		return 0, err
+	}
+
+	return size, nil
+}
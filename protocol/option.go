@@ -0,0 +1,140 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package protocol
+
+import (
+	"encoding/binary"
+	"errors"
+	"sort"
+)
+
+const (
+	// OptionSize size of file to send, uint64
+	OptionSize = 0x01
+	// OptionCodec ID of codec to compress file, see codec
+	OptionCodec = 0x02
+	// OptionDelta ask for block signatures of the old copy of file to send a
+	// delta, byte 1, see delta
+	OptionDelta = 0x03
+	// OptionHash MD5 of file to send, server skips the transfer if it has
+	// the same content
+	OptionHash = 0x04
+	// OptionFEC packs of a FEC group of UDP packs, byte, see fec
+	OptionFEC = 0x05
+	// OptionRoute next hops of a relayed TCP request after the server, as
+	// host:port,host:port, the server must be a relay
+	OptionRoute = 0x06
+
+	optionEnd = 0x00
+)
+
+// ErrOptionSpace error for options can't put into the first packet
+var ErrOptionSpace = errors.New("No space for request options")
+
+// Options are options of a request, they follow the file name in the first
+// packet as type(1), length(1) and value, and end with type 0 or the end of
+// packet. Server ignores options it doesn't know.
+type Options map[byte][]byte
+
+// ParseOptions parse options from b, which begins at HeaderSize of the first
+// packet
+func ParseOptions(b []byte) Options {
+	opts := make(Options)
+
+	for len(b) >= 2 && b[0] != optionEnd {
+		size := int(b[1])
+		if len(b) < 2+size {
+			break
+		}
+
+		opts[b[0]] = b[2 : 2+size]
+		b = b[2+size:]
+	}
+
+	return opts
+}
+
+// Marshal write options to b in order of type
+func (o Options) Marshal(b []byte) error {
+	types := make([]int, 0, len(o))
+	for t := range o {
+		types = append(types, int(t))
+	}
+	sort.Ints(types)
+
+	for _, t := range types {
+		value := o[byte(t)]
+		if len(value) > 255 || len(b) < 2+len(value) {
+			return ErrOptionSpace
+		}
+
+		b[0] = byte(t)
+		b[1] = byte(len(value))
+		b = b[2+copy(b[2:], value):]
+	}
+
+	if len(b) > 0 {
+		b[0] = optionEnd
+	}
+
+	return nil
+}
+
+// SetUint64 set option t to v
+func (o Options) SetUint64(t byte, v uint64) {
+	value := make([]byte, 8)
+	binary.BigEndian.PutUint64(value, v)
+	o[t] = value
+}
+
+// Uint64 return value of option t, false if not set
+func (o Options) Uint64(t byte) (uint64, bool) {
+	value, ok := o[t]
+	if !ok || len(value) != 8 {
+		return 0, false
+	}
+
+	return binary.BigEndian.Uint64(value), true
+}
+
+// SetByte set option t to v
+func (o Options) SetByte(t byte, v byte) {
+	o[t] = []byte{v}
+}
+
+// Byte return value of option t, false if not set
+func (o Options) Byte(t byte) (byte, bool) {
+	value, ok := o[t]
+	if !ok || len(value) != 1 {
+		return 0, false
+	}
+
+	return value[0], true
+}
@@ -59,6 +59,16 @@ const (
 	HeaderRequestType    = 0x10
 	HeaderFileType       = 0x20
 	HeaderFileFinishType = 0x30
+	HeaderGetType        = 0x40 // request for download a file from server
+	HeaderAckType        = 0x50 // reply of downloader on UDP, PackOrder is the reply
+	HeaderHelloType      = 0x60 // key exchange of encrypted UDP session
+	HeaderSealedType     = 0x70 // encrypted UDP packet, see udp/secure
+	HeaderAuthType       = 0x80 // answer of client to auth challenge, see auth
+	HeaderHoleType       = 0x90 // hole of file instead of file pack, body is its length, see sparse
+	HeaderParityType     = 0xA0 // parity of a group of UDP packs, see fec
+	HeaderProbeType      = 0xB0 // padded probe of UDP path MTU, server replies with its size
+	HeaderNackType       = 0xC0 // multicast receiver asks for lost packs, body is their orders
+	HeaderReportType     = 0xD0 // result of multicast receiver, PackOrder is the reply
 
 	// ReplyFinish - define Reply finish
 	ReplyFinish = 1<<32 - 1
@@ -66,6 +76,15 @@ const (
 
 	RepeatHandle = 1<<32 - 3
 
+	ReplyNotFound     = 1<<32 - 4
+	ReplyChallenge    = 1<<32 - 5 // followed by auth challenge
+	ReplyUnauthorized = 1<<32 - 6
+	ReplyForbidden    = 1<<32 - 7  // request denied by policy of server
+	ReplyBusy         = 1<<32 - 8  // too many connections
+	ReplyUnsupported  = 1<<32 - 9  // option of request not supported, such as codec
+	ReplySignature    = 1<<32 - 10 // followed by block signatures of old copy of file, see delta
+	ReplyPresent      = 1<<32 - 11 // server has the file already, nothing to send
+
 	// DefaultDir is default dir for save file
 	DefaultDir = "./"
 
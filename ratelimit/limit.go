@@ -0,0 +1,233 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package ratelimit
+
+import (
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sweepSize is the number of buckets in a group before unused ones are removed
+const sweepSize = 1024
+
+// ErrInvalidRate error for rate can't be parsed
+var ErrInvalidRate = errors.New("Invalid rate")
+
+var units = []struct {
+	suffix string
+	scale  float64
+}{
+	{"kib", 1 << 10},
+	{"mib", 1 << 20},
+	{"gib", 1 << 30},
+	{"kb", 1e3},
+	{"mb", 1e6},
+	{"gb", 1e9},
+	{"k", 1e3},
+	{"m", 1e6},
+	{"g", 1e9},
+	{"b", 1},
+}
+
+// ParseRate parse rate of bytes such as "500KB/s", "50MB/s" or "1.5GiB", KB
+// is 1000 bytes and KiB is 1024 bytes.
+func ParseRate(s string) (float64, error) {
+	text := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(s)), "/s")
+
+	scale := float64(1)
+	for _, unit := range units {
+		if strings.HasSuffix(text, unit.suffix) {
+			text = strings.TrimSuffix(text, unit.suffix)
+			scale = unit.scale
+			break
+		}
+	}
+
+	rate, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+	if err != nil || rate < 0 {
+		return 0, ErrInvalidRate
+	}
+
+	return rate * scale, nil
+}
+
+// NewByteBucket create a bucket limit bytes per second to rate, it holds
+// 100ms of bytes at most. It returns nil if rate is 0, which has no limit.
+func NewByteBucket(rate float64) *Bucket {
+	if rate <= 0 {
+		return nil
+	}
+
+	return NewBucket(rate, int(rate/10))
+}
+
+// Group holds a byte bucket of rate for each key, such as a client
+type Group struct {
+	rate float64
+
+	mu      sync.Mutex
+	buckets map[string]*Bucket
+}
+
+// NewGroup create a group, it returns nil if rate is 0, which has no limit
+func NewGroup(rate float64) *Group {
+	if rate <= 0 {
+		return nil
+	}
+
+	return &Group{
+		rate:    rate,
+		buckets: make(map[string]*Bucket),
+	}
+}
+
+// Get return bucket of key, nil if g is nil
+func (g *Group) Get(key string) *Bucket {
+	if g == nil {
+		return nil
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	b, ok := g.buckets[key]
+	if !ok {
+		if len(g.buckets) >= sweepSize {
+			for k, old := range g.buckets {
+				if old.Full() {
+					delete(g.buckets, k)
+				}
+			}
+		}
+
+		b = NewByteBucket(g.rate)
+		g.buckets[key] = b
+	}
+
+	return b
+}
+
+// Delay take n tokens from all buckets and return how long to wait
+func Delay(n int, buckets ...*Bucket) time.Duration {
+	var delay time.Duration
+
+	for _, b := range buckets {
+		if d := b.Reserve(n); d > delay {
+			delay = d
+		}
+	}
+
+	return delay
+}
+
+// conn limit rate of bytes read and written
+type conn struct {
+	net.Conn
+	buckets []*Bucket
+}
+
+// NewConn limit rate of bytes read from and written to c with buckets, nil
+// buckets are ignored, c is returned if there is no bucket.
+func NewConn(c net.Conn, buckets ...*Bucket) net.Conn {
+	var limits []*Bucket
+	for _, b := range buckets {
+		if b != nil {
+			limits = append(limits, b)
+		}
+	}
+
+	if len(limits) == 0 {
+		return c
+	}
+
+	return &conn{
+		Conn:    c,
+		buckets: limits,
+	}
+}
+
+func (c *conn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if n > 0 {
+		time.Sleep(Delay(n, c.buckets...))
+	}
+
+	return n, err
+}
+
+func (c *conn) Write(p []byte) (int, error) {
+	time.Sleep(Delay(len(p), c.buckets...))
+
+	return c.Conn.Write(p)
+}
+
+// reader limit rate of bytes read
+type reader struct {
+	io.Reader
+	buckets []*Bucket
+}
+
+// writer limit rate of bytes written
+type writer struct {
+	io.Writer
+	buckets []*Bucket
+}
+
+// NewReader limit rate of bytes read from r with buckets, nil buckets are
+// ignored
+func NewReader(r io.Reader, buckets ...*Bucket) io.Reader {
+	return &reader{Reader: r, buckets: buckets}
+}
+
+// NewWriter limit rate of bytes written to w with buckets, nil buckets are
+// ignored
+func NewWriter(w io.Writer, buckets ...*Bucket) io.Writer {
+	return &writer{Writer: w, buckets: buckets}
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if n > 0 {
+		time.Sleep(Delay(n, r.buckets...))
+	}
+
+	return n, err
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	time.Sleep(Delay(len(p), w.buckets...))
+
+	return w.Writer.Write(p)
+}
@@ -0,0 +1,166 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package ratelimit_test
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/TechCatsLab/redalert/ratelimit"
+)
+
+func TestParseRate(t *testing.T) {
+	cases := []struct {
+		text string
+		rate float64
+		err  error
+	}{
+		{"1000", 1000, nil},
+		{"500KB/s", 500e3, nil},
+		{"50MB/s", 50e6, nil},
+		{"1.5GiB", 1.5 * (1 << 30), nil},
+		{"64kib/s", 64 << 10, nil},
+		{" 2 m ", 2e6, nil},
+		{"10b", 10, nil},
+		{"0", 0, nil},
+		{"-1MB", 0, ratelimit.ErrInvalidRate},
+		{"fast", 0, ratelimit.ErrInvalidRate},
+		{"", 0, ratelimit.ErrInvalidRate},
+	}
+
+	for _, c := range cases {
+		rate, err := ratelimit.ParseRate(c.text)
+		if rate != c.rate || err != c.err {
+			t.Errorf("parse %q: got %v %v, want %v %v", c.text, rate, err, c.rate, c.err)
+		}
+	}
+}
+
+func TestAllow(t *testing.T) {
+	b := ratelimit.NewBucket(10, 2)
+
+	if !b.Allow() || !b.Allow() {
+		t.Fatal("new bucket isn't full")
+	}
+	if b.Allow() {
+		t.Fatal("empty bucket allows")
+	}
+
+	// a token is added every 100ms
+	time.Sleep(150 * time.Millisecond)
+	if !b.Allow() {
+		t.Fatal("bucket isn't refilled")
+	}
+	if b.Allow() {
+		t.Fatal("bucket is refilled too fast")
+	}
+}
+
+func TestReserve(t *testing.T) {
+	cases := []struct {
+		name string
+		n    []int         // tokens reserved in turn
+		want time.Duration // wait of the last reserve
+	}{
+		{"within burst", []int{50, 50}, 0},
+		{"debt", []int{100, 500}, 500 * time.Millisecond},
+		{"larger than burst", []int{1100}, time.Second},
+		{"growing debt", []int{100, 100, 100}, 200 * time.Millisecond},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			b := ratelimit.NewBucket(1000, 100)
+
+			var got time.Duration
+			for _, n := range c.n {
+				got = b.Reserve(n)
+			}
+
+			// time passes between reserves, the bucket refills a little
+			if got > c.want || got < c.want-20*time.Millisecond {
+				t.Fatalf("wait %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestWriterRate(t *testing.T) {
+	const rate = 100000
+
+	w := ratelimit.NewWriter(io.Discard, ratelimit.NewByteBucket(rate))
+	chunk := make([]byte, 1000)
+
+	// a byte bucket holds 100ms of bytes, the rest takes 200ms
+	begin := time.Now()
+	for i := 0; i < 30; i++ {
+		w.Write(chunk)
+	}
+
+	if took := time.Since(begin); took < 150*time.Millisecond || took > time.Second {
+		t.Fatalf("30KB at %d B/s took %v", rate, took)
+	}
+}
+
+func TestNoLimit(t *testing.T) {
+	var b *ratelimit.Bucket
+	if b.Reserve(1<<30) != 0 {
+		t.Fatal("nil bucket limits")
+	}
+
+	if ratelimit.NewByteBucket(0) != nil || ratelimit.NewGroup(0) != nil {
+		t.Fatal("rate 0 limits")
+	}
+
+	var g *ratelimit.Group
+	if g.Get("alice") != nil {
+		t.Fatal("nil group has a bucket")
+	}
+}
+
+func TestGroup(t *testing.T) {
+	g := ratelimit.NewGroup(1000)
+
+	if g.Get("alice") != g.Get("alice") {
+		t.Fatal("client gets a new bucket")
+	}
+	if g.Get("alice") == g.Get("bob") {
+		t.Fatal("clients share a bucket")
+	}
+
+	// each client has its own rate
+	if ratelimit.Delay(100, g.Get("alice")) != 0 || ratelimit.Delay(100, g.Get("bob")) != 0 {
+		t.Fatal("bucket of client isn't full")
+	}
+	if ratelimit.Delay(100, g.Get("alice"), g.Get("bob")) == 0 {
+		t.Fatal("delay isn't the longest wait of buckets")
+	}
+}
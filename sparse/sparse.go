@@ -0,0 +1,175 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package sparse
+
+import (
+	"io"
+	"os"
+)
+
+const (
+	// HoleSize size of body of a hole pack, the length of hole as uint64
+	HoleSize = 8
+	// MaxHole longest hole reported at a time, a longer one is reported in
+	// parts, so the receiver hashing its zeros replies before client gives up
+	MaxHole = 1 << 30
+)
+
+var zeros = make([]byte, 32*1024)
+
+// Reader read a file by data and holes. Holes are found by SEEK_DATA and
+// SEEK_HOLE where the system supports them, otherwise a read of zeros is
+// reported as a hole.
+type Reader struct {
+	file   *os.File
+	size   int64
+	offset int64
+	seek   bool  // file supports SEEK_DATA and SEEK_HOLE
+	data   int64 // start of the next data region
+	end    int64 // end of the next data region
+}
+
+// NewReader create a Reader of file
+func NewReader(file *os.File) (*Reader, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Reader{
+		file: file,
+		size: info.Size(),
+		seek: true,
+	}, nil
+}
+
+// Next read data at offset into p and return its length, or return length
+// of the hole at offset, io.EOF at the end of file
+func (r *Reader) Next(p []byte) (int, int64, error) {
+	if r.offset >= r.size {
+		return 0, 0, io.EOF
+	}
+
+	if r.seek && r.offset >= r.end {
+		data, end, err := dataRegion(r.file, r.offset, r.size)
+		if err != nil {
+			r.seek = false
+		}
+
+		r.data, r.end = data, end
+	}
+
+	if r.seek {
+		if r.offset < r.data {
+			hole := r.data - r.offset
+			if hole > MaxHole {
+				hole = MaxHole
+			}
+			r.offset += hole
+
+			return 0, hole, nil
+		}
+
+		if max := r.end - r.offset; int64(len(p)) > max {
+			p = p[:max]
+		}
+	}
+
+	if max := r.size - r.offset; int64(len(p)) > max {
+		p = p[:max]
+	}
+
+	n, err := r.file.ReadAt(p, r.offset)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	r.offset += int64(n)
+
+	if !r.seek && isZero(p[:n]) {
+		return 0, int64(n) + r.zeros(p), nil
+	}
+
+	return n, 0, nil
+}
+
+// zeros read blocks of zeros at offset into p and return their length, the
+// first block with data is left to be read by Next, so adjacent blocks of
+// zeros are reported as one hole
+func (r *Reader) zeros(p []byte) int64 {
+	var hole int64
+
+	for r.offset < r.size && hole < MaxHole {
+		if max := r.size - r.offset; int64(len(p)) > max {
+			p = p[:max]
+		}
+
+		n, err := r.file.ReadAt(p, r.offset)
+		if n == 0 || err != nil && err != io.EOF || !isZero(p[:n]) {
+			break
+		}
+
+		r.offset += int64(n)
+		hole += int64(n)
+	}
+
+	return hole
+}
+
+// WriteZeros write n zeros to w
+func WriteZeros(w io.Writer, n int64) error {
+	for n > 0 {
+		chunk := zeros
+		if n < int64(len(chunk)) {
+			chunk = chunk[:n]
+		}
+
+		if _, err := w.Write(chunk); err != nil {
+			return err
+		}
+
+		n -= int64(len(chunk))
+	}
+
+	return nil
+}
+
+func isZero(p []byte) bool {
+	for _, b := range p {
+		if b != 0 {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,183 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package sparse_test
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/TechCatsLab/redalert/sparse"
+)
+
+// region is data written at offset of a sparse file
+type region struct {
+	offset int64
+	data   []byte
+}
+
+// sparseFile create a file of size with regions written, the rest is holes
+func sparseFile(t *testing.T, size int64, regions []region) *os.File {
+	f, err := os.Create(filepath.Join(t.TempDir(), "sparse"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+
+	if err = f.Truncate(size); err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range regions {
+		if _, err = f.WriteAt(r.data, r.offset); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return f
+}
+
+// readAll read file by r, holes are read as zeros
+func readAll(t *testing.T, r *sparse.Reader) []byte {
+	var (
+		content []byte
+		buf     = make([]byte, 4096)
+	)
+
+	for {
+		n, hole, err := r.Next(buf)
+		if err == io.EOF {
+			return content
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if n > 0 && hole > 0 {
+			t.Fatalf("got %d bytes and a hole of %d", n, hole)
+		}
+
+		content = append(content, buf[:n]...)
+		content = append(content, make([]byte, hole)...)
+	}
+}
+
+func TestReader(t *testing.T) {
+	data := bytes.Repeat([]byte("data"), 5000)
+
+	cases := []struct {
+		name    string
+		size    int64
+		regions []region
+	}{
+		{"no holes", int64(len(data)), []region{{0, data}}},
+		{"hole in the middle", 1 << 20, []region{{0, data}, {1<<20 - int64(len(data)), data}}},
+		{"leading hole", 1 << 20, []region{{1<<20 - int64(len(data)), data}}},
+		{"trailing hole", 1 << 20, []region{{0, data}}},
+		{"all hole", 1 << 20, nil},
+		{"written zeros", 1 << 16, []region{{0, make([]byte, 1<<16)}}},
+		{"empty", 0, nil},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			f := sparseFile(t, c.size, c.regions)
+
+			want := make([]byte, c.size)
+			for _, r := range c.regions {
+				copy(want[r.offset:], r.data)
+			}
+
+			r, err := sparse.NewReader(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(readAll(t, r), want) {
+				t.Fatal("content read differs")
+			}
+		})
+	}
+}
+
+// a long hole is reported in parts of MaxHole at most
+func TestReaderLongHole(t *testing.T) {
+	if testing.Short() {
+		t.Skip("reads zeros if the file system has no SEEK_DATA")
+	}
+
+	size := int64(2*sparse.MaxHole + 100)
+	f := sparseFile(t, size, []region{{size - 4, []byte("tail")}})
+
+	r, err := sparse.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		offset int64
+		buf    = make([]byte, 4096)
+	)
+	for {
+		n, hole, err := r.Next(buf)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if hole > sparse.MaxHole {
+			t.Fatalf("hole of %d is longer than %d", hole, sparse.MaxHole)
+		}
+		if n > 0 && offset+int64(n) == size && !bytes.HasSuffix(buf[:n], []byte("tail")) {
+			t.Fatal("tail of file differs")
+		}
+		offset += int64(n) + hole
+	}
+
+	if offset != size {
+		t.Fatalf("read %d bytes, want %d", offset, size)
+	}
+}
+
+func TestWriteZeros(t *testing.T) {
+	for _, n := range []int64{0, 1, 32 * 1024, 100000} {
+		var buf bytes.Buffer
+		if err := sparse.WriteZeros(&buf, n); err != nil {
+			t.Fatal(err)
+		}
+
+		if int64(buf.Len()) != n || !bytes.Equal(buf.Bytes(), make([]byte, n)) {
+			t.Fatalf("wrote %d bytes, want %d zeros", buf.Len(), n)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package storage
+
+import (
+	"os"
+)
+
+// Callback hands every chunk of an incoming file to Write, and reports the
+// end of the transfer to Done. The chunk is only valid during the call.
+// Nothing is kept, so Stat always reports ErrNotExist.
+type Callback struct {
+	Write func(name string, p []byte, off int64) error
+	Done  func(name string, err error) // optional
+}
+
+type callbackObject struct {
+	storage *Callback
+	name    string
+}
+
+// Create start a new transfer
+func (c *Callback) Create(name string) (Object, error) {
+	return &callbackObject{storage: c, name: name}, nil
+}
+
+// Stat always return ErrNotExist
+func (c *Callback) Stat(name string) (*Info, error) {
+	return nil, &os.PathError{Op: "stat", Path: name, Err: ErrNotExist}
+}
+
+func (o *callbackObject) WriteAt(p []byte, off int64) (int, error) {
+	if err := o.storage.Write(o.name, p, off); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+func (o *callbackObject) Commit() error {
+	if o.storage.Done != nil {
+		o.storage.Done(o.name, nil)
+	}
+
+	return nil
+}
+
+func (o *callbackObject) Abort() error {
+	if o.storage.Done != nil {
+		o.storage.Done(o.name, ErrAborted)
+	}
+
+	return nil
+}
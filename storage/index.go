@@ -0,0 +1,193 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package storage
+
+import (
+	"bytes"
+	"crypto/md5"
+	"io"
+	"sync"
+	"time"
+)
+
+// Digest identify content of a file by its MD5 and size
+type Digest struct {
+	Sum  [md5.Size]byte
+	Size int64
+}
+
+// Index remember content of files in a storage by digest, so a file with
+// the same content as a file to receive can be found without reading every
+// file. A file changed by others since it's indexed is hashed again.
+type Index struct {
+	storage Storage
+
+	mu    sync.Mutex
+	names map[string]indexEntry
+	sums  map[Digest]string
+}
+
+type indexEntry struct {
+	digest  Digest
+	modTime time.Time
+}
+
+// NewIndex create an empty Index of s
+func NewIndex(s Storage) *Index {
+	return &Index{
+		storage: s,
+		names:   make(map[string]indexEntry),
+		sums:    make(map[Digest]string),
+	}
+}
+
+// Add record that the committed file name has content of d
+func (x *Index) Add(name string, d Digest) {
+	info, err := x.storage.Stat(name)
+	if err != nil || info.Size != d.Size {
+		return
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	x.names[name] = indexEntry{digest: d, modTime: info.ModTime}
+	x.sums[d] = name
+}
+
+// Ensure make sure name has content of d, by finding it there or copying
+// from another file of the same content which readable allows, it returns
+// true if name has it
+func (x *Index) Ensure(name string, d Digest, readable func(string) bool) bool {
+	if x.has(name, d) {
+		return true
+	}
+
+	x.mu.Lock()
+	other, ok := x.sums[d]
+	x.mu.Unlock()
+
+	if !ok || other == name || !readable(other) || !x.has(other, d) {
+		return false
+	}
+
+	if err := x.copy(other, name, d); err != nil {
+		return false
+	}
+
+	x.Add(name, d)
+
+	return true
+}
+
+// has tell if name has content of d, name is hashed if it isn't indexed
+// or changed since
+func (x *Index) has(name string, d Digest) bool {
+	info, err := x.storage.Stat(name)
+	if err != nil || info.Size != d.Size {
+		return false
+	}
+
+	x.mu.Lock()
+	entry, ok := x.names[name]
+	x.mu.Unlock()
+
+	if ok && entry.modTime.Equal(info.ModTime) {
+		return entry.digest == d
+	}
+
+	source, ok := x.storage.(Source)
+	if !ok {
+		return false
+	}
+
+	file, err := source.Open(name)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	hash := md5.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return false
+	}
+
+	found := Digest{Size: size}
+	copy(found.Sum[:], hash.Sum(nil))
+
+	x.mu.Lock()
+	x.names[name] = indexEntry{digest: found, modTime: info.ModTime}
+	x.sums[found] = name
+	x.mu.Unlock()
+
+	return found == d
+}
+
+// copy copy file from to name to, the copy must have content of d
+func (x *Index) copy(from, to string, d Digest) error {
+	file, err := x.storage.(Source).Open(from)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	object, err := x.storage.Create(to)
+	if err != nil {
+		return err
+	}
+
+	hash := md5.New()
+	w := &objectWriter{Object: object}
+	if _, err = io.Copy(io.MultiWriter(w, hash), file); err != nil {
+		object.Abort()
+		return err
+	}
+
+	if w.offset != d.Size || !bytes.Equal(hash.Sum(nil), d.Sum[:]) {
+		object.Abort()
+		return ErrAborted
+	}
+
+	return object.Commit()
+}
+
+// objectWriter write to Object sequentially
+type objectWriter struct {
+	Object
+	offset int64
+}
+
+func (w *objectWriter) Write(p []byte) (int, error) {
+	n, err := w.WriteAt(p, w.offset)
+	w.offset += int64(n)
+
+	return n, err
+}
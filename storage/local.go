@@ -0,0 +1,111 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package storage
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Local stores files in a directory of the local file system. Files are
+// written to a temporary name and renamed on Commit.
+type Local struct {
+	dir string
+}
+
+type localObject struct {
+	*os.File
+	path string
+}
+
+// NewLocal create a Local storage under dir
+func NewLocal(dir string) *Local {
+	return &Local{
+		dir: dir,
+	}
+}
+
+// path return the path of name under l.dir, the name can't escape from it
+func (l *Local) path(name string) string {
+	return filepath.Join(l.dir, filepath.Clean("/"+name))
+}
+
+// Create create a temporary file next to the target
+func (l *Local) Create(name string) (Object, error) {
+	path := l.path(name)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".part-*")
+	if err != nil {
+		return nil, err
+	}
+
+	return &localObject{File: file, path: path}, nil
+}
+
+// Stat return info of a committed file
+func (l *Local) Stat(name string) (*Info, error) {
+	fileInfo, err := os.Stat(l.path(name))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Info{
+		Name:    name,
+		Size:    fileInfo.Size(),
+		ModTime: fileInfo.ModTime(),
+	}, nil
+}
+
+// Open open a committed file for reading
+func (l *Local) Open(name string) (io.ReadCloser, error) {
+	return os.Open(l.path(name))
+}
+
+// Commit move the temporary file to its name
+func (o *localObject) Commit() error {
+	if err := o.File.Close(); err != nil {
+		os.Remove(o.Name())
+		return err
+	}
+
+	return os.Rename(o.Name(), o.path)
+}
+
+// Abort close and remove the temporary file
+func (o *localObject) Abort() error {
+	o.File.Close()
+
+	return os.Remove(o.Name())
+}
@@ -0,0 +1,156 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package storage
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Memory keeps files in memory.
+type Memory struct {
+	mu    sync.Mutex
+	files map[string]*memoryFile
+}
+
+type memoryFile struct {
+	content []byte
+	modTime time.Time
+}
+
+// memoryReader reader of a committed file, it's an io.ReaderAt too
+type memoryReader struct {
+	*bytes.Reader
+}
+
+type memoryObject struct {
+	storage *Memory
+	name    string
+	content []byte
+}
+
+// NewMemory create an empty Memory storage
+func NewMemory() *Memory {
+	return &Memory{
+		files: make(map[string]*memoryFile),
+	}
+}
+
+// Create start receiving a file, it's visible after committed
+func (m *Memory) Create(name string) (Object, error) {
+	return &memoryObject{storage: m, name: name}, nil
+}
+
+// Stat return info of a committed file
+func (m *Memory) Stat(name string) (*Info, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	file, ok := m.files[name]
+	if !ok {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: ErrNotExist}
+	}
+
+	return &Info{
+		Name:    name,
+		Size:    int64(len(file.content)),
+		ModTime: file.modTime,
+	}, nil
+}
+
+// Open open a committed file for reading
+func (m *Memory) Open(name string) (io.ReadCloser, error) {
+	content, ok := m.Bytes(name)
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: name, Err: ErrNotExist}
+	}
+
+	return memoryReader{bytes.NewReader(content)}, nil
+}
+
+func (memoryReader) Close() error {
+	return nil
+}
+
+// Bytes return content of a committed file
+func (m *Memory) Bytes(name string) ([]byte, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	file, ok := m.files[name]
+	if !ok {
+		return nil, false
+	}
+
+	return file.content, true
+}
+
+// Remove delete a committed file
+func (m *Memory) Remove(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.files, name)
+}
+
+func (o *memoryObject) WriteAt(p []byte, off int64) (int, error) {
+	if end := off + int64(len(p)); end > int64(len(o.content)) {
+		if end > int64(cap(o.content)) {
+			content := make([]byte, end, 2*end)
+			copy(content, o.content)
+			o.content = content
+		} else {
+			o.content = o.content[:end]
+		}
+	}
+
+	return copy(o.content[off:], p), nil
+}
+
+func (o *memoryObject) Commit() error {
+	o.storage.mu.Lock()
+	defer o.storage.mu.Unlock()
+
+	o.storage.files[o.name] = &memoryFile{
+		content: o.content,
+		modTime: time.Now(),
+	}
+
+	return nil
+}
+
+func (o *memoryObject) Abort() error {
+	o.content = nil
+
+	return nil
+}
@@ -0,0 +1,108 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package storage
+
+import (
+	"errors"
+	"io"
+	"os"
+	"time"
+)
+
+var (
+	// ErrBusy error for storage can only receive one file at a time
+	ErrBusy = errors.New("Storage is busy")
+	// ErrAborted error reported to Done callbacks when a transfer was aborted
+	ErrAborted = errors.New("Transfer aborted")
+	// ErrNotSequential error for storage can only be written in order
+	ErrNotSequential = errors.New("Write is not sequential")
+	// ErrNotExist error for file not found in storage
+	ErrNotExist = os.ErrNotExist
+)
+
+// Storage is the backend where received files are kept.
+type Storage interface {
+	Create(name string) (Object, error)
+	Stat(name string) (*Info, error)
+}
+
+// Source is a storage whose files can be read back, it's used to serve
+// downloads.
+type Source interface {
+	Open(name string) (io.ReadCloser, error)
+}
+
+// Object is a file being received. It becomes visible under its name after
+// Commit, which is called once the file has been received and its hash
+// checked. Abort discards it when the transfer fails.
+type Object interface {
+	WriteAt(p []byte, off int64) (int, error)
+	Commit() error
+	Abort() error
+}
+
+// Truncater is an Object which can be resized, a region extended by
+// Truncate or skipped by WriteAt reads as zeros and is kept as a hole
+type Truncater interface {
+	Truncate(size int64) error
+}
+
+// Hole make n bytes of o at off zeros, they're a hole if o is a Truncater,
+// otherwise zeros are written
+func Hole(o Object, off, n int64) error {
+	if t, ok := o.(Truncater); ok {
+		return t.Truncate(off + n)
+	}
+
+	zeros := make([]byte, 32*1024)
+	for n > 0 {
+		chunk := zeros
+		if n < int64(len(chunk)) {
+			chunk = chunk[:n]
+		}
+
+		written, err := o.WriteAt(chunk, off)
+		if err != nil {
+			return err
+		}
+
+		off += int64(written)
+		n -= int64(written)
+	}
+
+	return nil
+}
+
+// Info describe a stored file
+type Info struct {
+	Name    string
+	Size    int64
+	ModTime time.Time
+}
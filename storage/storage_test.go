@@ -0,0 +1,211 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package storage_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/TechCatsLab/redalert/storage"
+)
+
+// put write content as name to s and commit it
+func put(t *testing.T, s storage.Storage, name string, content []byte) {
+	o, err := s.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = o.WriteAt(content, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err = o.Commit(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLocalPath(t *testing.T) {
+	cases := []struct {
+		name string
+		path string // path under dir of storage
+	}{
+		{"a.bin", "a.bin"},
+		{"sub/dir/a.bin", "sub/dir/a.bin"},
+		{"/etc/a.bin", "etc/a.bin"},
+		{"../a.bin", "a.bin"},
+		{"sub/../../../a.bin", "a.bin"},
+		{"./sub//a.bin", "sub/a.bin"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			parent := t.TempDir()
+			dir := filepath.Join(parent, "root")
+			s := storage.NewLocal(dir)
+
+			put(t, s, c.name, []byte("content"))
+
+			data, err := os.ReadFile(filepath.Join(dir, c.path))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != "content" {
+				t.Fatalf("got %q", data)
+			}
+
+			// nothing is written out of dir
+			entries, err := os.ReadDir(parent)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 {
+				t.Fatalf("%d entries next to dir of storage", len(entries))
+			}
+
+			if info, err := s.Stat(c.name); err != nil || info.Size != 7 {
+				t.Fatalf("stat: %v %v", info, err)
+			}
+		})
+	}
+}
+
+func TestLocalAbort(t *testing.T) {
+	dir := t.TempDir()
+	s := storage.NewLocal(dir)
+
+	o, err := s.Create("a.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	o.WriteAt([]byte("part"), 0)
+
+	// the file isn't visible until committed
+	if _, err = s.Stat("a.bin"); !errors.Is(err, storage.ErrNotExist) {
+		t.Fatalf("got %v, want %v", err, storage.ErrNotExist)
+	}
+
+	if err = o.Abort(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("%s is left after abort", entries[0].Name())
+	}
+}
+
+func TestHole(t *testing.T) {
+	stores := map[string]storage.Storage{
+		"local":  storage.NewLocal(t.TempDir()),
+		"memory": storage.NewMemory(),
+	}
+
+	want := append(append([]byte("head"), make([]byte, 100000)...), "tail"...)
+
+	for name, s := range stores {
+		t.Run(name, func(t *testing.T) {
+			o, err := s.Create("a.bin")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			o.WriteAt([]byte("head"), 0)
+			if err = storage.Hole(o, 4, 100000); err != nil {
+				t.Fatal(err)
+			}
+			o.WriteAt([]byte("tail"), 100004)
+			if err = o.Commit(); err != nil {
+				t.Fatal(err)
+			}
+
+			r, err := s.(storage.Source).Open("a.bin")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+
+			var got bytes.Buffer
+			got.ReadFrom(r)
+			if !bytes.Equal(got.Bytes(), want) {
+				t.Fatal("content with hole differs")
+			}
+		})
+	}
+}
+
+func TestWriter(t *testing.T) {
+	var (
+		out  bytes.Buffer
+		done []error
+	)
+	w := &storage.Writer{
+		W:    &out,
+		Done: func(name string, err error) { done = append(done, err) },
+	}
+
+	o, err := w.Create("a.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// one transfer at a time
+	if _, err = w.Create("b.bin"); err != storage.ErrBusy {
+		t.Fatalf("got %v, want %v", err, storage.ErrBusy)
+	}
+
+	o.WriteAt([]byte("abc"), 0)
+	if _, err = o.WriteAt([]byte("x"), 10); err != storage.ErrNotSequential {
+		t.Fatalf("got %v, want %v", err, storage.ErrNotSequential)
+	}
+	o.WriteAt([]byte("def"), 3)
+	o.Abort()
+
+	if o, err = w.Create("b.bin"); err != nil {
+		t.Fatal(err)
+	}
+	o.Commit()
+
+	if out.String() != "abcdef" {
+		t.Fatalf("got %q", out.String())
+	}
+	if len(done) != 2 || done[0] != storage.ErrAborted || done[1] != nil {
+		t.Fatalf("done with %v", done)
+	}
+
+	if _, err = w.Stat("a.bin"); !errors.Is(err, storage.ErrNotExist) {
+		t.Fatalf("got %v, want %v", err, storage.ErrNotExist)
+	}
+}
@@ -0,0 +1,105 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package storage
+
+import (
+	"io"
+	"os"
+	"sync"
+)
+
+// Writer streams incoming files to W and accepts one transfer at a time.
+// Every file must be written in order, and data already written can't be
+// taken back when a transfer is aborted. Nothing is kept, so Stat always
+// reports ErrNotExist.
+type Writer struct {
+	W    io.Writer
+	Done func(name string, err error) // Called when a transfer closed or aborted, optional
+
+	mu   sync.Mutex
+	busy bool
+}
+
+type writerObject struct {
+	storage *Writer
+	name    string
+	offset  int64
+}
+
+// Create start a new transfer, return ErrBusy if another one is running
+func (w *Writer) Create(name string) (Object, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.busy {
+		return nil, ErrBusy
+	}
+	w.busy = true
+
+	return &writerObject{storage: w, name: name}, nil
+}
+
+// Stat always return ErrNotExist
+func (w *Writer) Stat(name string) (*Info, error) {
+	return nil, &os.PathError{Op: "stat", Path: name, Err: ErrNotExist}
+}
+
+func (w *Writer) finish(name string, err error) {
+	w.mu.Lock()
+	w.busy = false
+	w.mu.Unlock()
+
+	if w.Done != nil {
+		w.Done(name, err)
+	}
+}
+
+func (o *writerObject) WriteAt(p []byte, off int64) (int, error) {
+	if off != o.offset {
+		return 0, ErrNotSequential
+	}
+
+	n, err := o.storage.W.Write(p)
+	o.offset += int64(n)
+
+	return n, err
+}
+
+func (o *writerObject) Commit() error {
+	o.storage.finish(o.name, nil)
+
+	return nil
+}
+
+func (o *writerObject) Abort() error {
+	o.storage.finish(o.name, ErrAborted)
+
+	return nil
+}
@@ -0,0 +1,69 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package client
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+
+	"github.com/TechCatsLab/redalert/auth"
+	"github.com/TechCatsLab/redalert/protocol"
+)
+
+// readReply read a reply of server into buffer reply, answer the auth
+// challenge of request with key if server asks for one
+func readReply(conn net.Conn, reply []byte, key *auth.Key, request []byte) (uint32, error) {
+	for {
+		if _, err := io.ReadFull(conn, reply); err != nil {
+			return 0, err
+		}
+
+		switch binary.BigEndian.Uint32(reply) {
+		case protocol.ReplyChallenge:
+			if key == nil {
+				return 0, auth.ErrRequired
+			}
+
+			challenge := make([]byte, auth.ChallengeSize)
+			if _, err := io.ReadFull(conn, challenge); err != nil {
+				return 0, err
+			}
+
+			if _, err := conn.Write(auth.Marshal(key, challenge, request)); err != nil {
+				return 0, err
+			}
+		case protocol.ReplyUnauthorized:
+			return 0, auth.ErrUnauthorized
+		default:
+			return binary.BigEndian.Uint32(reply), nil
+		}
+	}
+}
@@ -30,12 +30,15 @@
 package client
 
 import (
-	"encoding/binary"
-	"fmt"
-	"log"
+	"crypto/tls"
 	"net"
+	"os"
 
+	"github.com/TechCatsLab/redalert/auth"
+	"github.com/TechCatsLab/redalert/delta"
+	"github.com/TechCatsLab/redalert/logger"
 	"github.com/TechCatsLab/redalert/protocol"
+	"github.com/TechCatsLab/redalert/ratelimit"
 )
 
 const (
@@ -49,38 +52,71 @@ type (
 		Port     string
 		FileName string
 		PackSize int
+		TLS      *tls.Config // Use TLS if not nil
+		Key      *auth.Key   // Answer auth challenge of server if not nil
+		Dest     string      // Path of file on server, base name of FileName if empty
+		Limit    float64     // Bytes per second, no limit if 0
+		Codec    byte        // ID of codec to compress file, see codec, not compressed if 0
+		Delta    bool        // Send delta against the old copy of file on server if there is one
+		SkipSame bool        // Send MD5 of file, nothing is sent if server has the same file
+		Sparse   bool        // Send holes of file as their length, unless compressed or delta
+		Route    string      // Next hops after server as host:port,host:port, server relays file to the last one
+		Network  string      // tcp or unix with Address as path of socket, default to tcp
+
+		Dialer func() (net.Conn, error) // Dial server by it instead of Network if not nil
+		Log    logger.Logger            // Where messages go, default to logger.Default()
 	}
 
 	// Client - TCP client
 	Client struct {
 		conf   *Conf
-		conn   *net.TCPConn
+		conn   net.Conn
 		proto  *protocol.Proto
 		handle handler
 		info   *FileInfo
-		close  chan struct{}
+		log    logger.Logger
 	}
 )
 
-// NewClient create a new tcp client
+// NewClient create a new tcp client, the process exits on error
 func NewClient(conf *Conf) *Client {
-	addr, err := net.ResolveTCPAddr("tcp", conf.Address+":"+conf.Port)
+	client, err := newClient(conf)
 	if err != nil {
-		log.Fatalf("[ERROR] Can't resolve tcp address with error %v \n", err)
-		return nil
+		logger.Or(conf.Log).Error("dial error", "server", conf.Address, "err", err)
+		os.Exit(1)
 	}
 
-	conn, err := net.DialTCP("tcp", nil, addr)
+	return client
+}
+
+// Send send file of conf to server, it returns once the file is sent
+func Send(conf *Conf) error {
+	c, err := newClient(conf)
 	if err != nil {
-		log.Fatalf("[ERROR] Dial crash with error: %v \n", err)
-		return nil
+		return err
+	}
+	defer c.info.file.Close()
+	defer c.conn.Close()
+
+	return c.run()
+}
+
+// newClient dial server and open file of conf
+func newClient(conf *Conf) (*Client, error) {
+	conn, err := dial(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	if conf.PackSize == 0 {
+		conf.PackSize = protocol.FirstPacketSize
 	}
 
 	client := &Client{
 		conf:  conf,
 		conn:  conn,
 		proto: &protocol.Proto{},
-		close: make(chan struct{}),
+		log:   logger.Or(conf.Log).With("server", conn.RemoteAddr().String(), "file", conf.FileName),
 		info: &FileInfo{
 			filePack:  make([]byte, conf.PackSize),
 			replyPack: make([]byte, protocol.ReplySize),
@@ -91,62 +127,156 @@ func NewClient(conf *Conf) *Client {
 	client.handle = &Provider{
 		client: client,
 	}
-	client.prepareBuffer()
 
 	if err = client.info.initFile(conf.FileName); err != nil {
-		client.handle.OnError(err)
+		conn.Close()
+		return nil, err
 	}
 
-	return client
+	return client, nil
 }
 
-// Start start sending
+// Start start sending, the process exits once the file is sent or on error
 func (c *Client) Start() {
-	c.receive(c.conn)
+	if err := c.run(); err != nil {
+		c.handle.OnError(err)
+	}
+
+	c.handle.OnClose()
 }
 
-func (c *Client) prepareBuffer() {
-	c.conn.SetReadBuffer(bufferSize)
-	c.conn.SetWriteBuffer(bufferSize)
+// dial connect to server, and finish TLS handshake if conf.TLS is set, the
+// conn is limited to conf.Limit
+func dial(conf *Conf) (net.Conn, error) {
+	var (
+		conn net.Conn
+		err  error
+	)
+
+	switch {
+	case conf.Dialer != nil:
+		conn, err = conf.Dialer()
+	case conf.Network == "" || conf.Network == "tcp":
+		conn, err = dialTCP(conf)
+	case conf.Network == "unix":
+		conn, err = net.Dial("unix", conf.Address)
+	default:
+		err = errNetwork
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if conf.TLS == nil {
+		return ratelimit.NewConn(conn, ratelimit.NewByteBucket(conf.Limit)), nil
+	}
+
+	tlsConf := conf.TLS
+	if tlsConf.ServerName == "" {
+		tlsConf = tlsConf.Clone()
+		tlsConf.ServerName = conf.Address
+	}
+
+	tlsConn := tls.Client(conn, tlsConf)
+	if err = tlsConn.Handshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return ratelimit.NewConn(tlsConn, ratelimit.NewByteBucket(conf.Limit)), nil
 }
 
-func (c *Client) receive(conn *net.TCPConn) {
-	go func() {
-		select {
-		case <-c.close:
-			c.handle.OnClose()
-		}
-	}()
+// dialTCP connect server at conf.Address and conf.Port
+func dialTCP(conf *Conf) (net.Conn, error) {
+	addr, err := net.ResolveTCPAddr("tcp", net.JoinHostPort(conf.Address, conf.Port))
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.DialTCP("tcp", nil, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	conn.SetReadBuffer(bufferSize)
+	conn.SetWriteBuffer(bufferSize)
+
+	return conn, nil
+}
 
+// run send request and file packs on replies of server until the server
+// replies ReplyFinish to the finish pack
+func (c *Client) run() error {
 	if err := c.info.consult(); err != nil {
-		c.handle.OnError(err)
+		return err
 	}
 
 	c.proto.HeaderSize = protocol.FixedHeaderSize
 	for {
-		_, err := conn.Read(c.info.replyPack)
+		packOrder, err := readReply(c.conn, c.info.replyPack, c.conf.Key, c.info.headPack)
 		if err != nil {
-			c.handle.OnError(err)
+			return err
 		}
 
-		packOrder := binary.BigEndian.Uint32(c.info.replyPack)
-		fmt.Printf("[RECEIVE] pack order %d \n", packOrder)
+		c.log.Debug("receive reply", "order", packOrder)
+
+		if packOrder == protocol.ReplyFinish && c.info.filePack[0] == protocol.HeaderFileFinishType {
+			return nil
+		}
 
 		if packOrder == protocol.ReplyError {
-			c.handle.OnError(errFromServer)
+			return errFromServer
 		}
 
-		if packOrder != c.proto.PackOrder {
-			c.handle.OnError(errPackOrder)
+		if packOrder == protocol.ReplyForbidden {
+			return ErrForbidden
 		}
 
-		err = c.info.SendFile(c.conf.PackSize)
-		if err != nil {
-			c.handle.OnError(err)
+		if packOrder == protocol.ReplyBusy {
+			return errBusy
+		}
+
+		if packOrder == protocol.ReplyUnsupported {
+			if c.conf.Route != "" {
+				return errRelay
+			}
+			return errCodec
+		}
+
+		if packOrder == protocol.ReplyPresent {
+			c.log.Info("file is present on server")
+			return nil
+		}
+
+		if packOrder == protocol.ReplySignature {
+			sig, err := delta.ReadSignature(c.conn)
+			if err != nil {
+				return err
+			}
+
+			c.log.Debug("receive signature", "blocks", len(sig.Blocks))
+
+			if err = c.info.encode(sig); err != nil {
+				return err
+			}
+			packOrder = 0
+		} else if packOrder == 0 && c.proto.PackOrder == 0 {
+			if err = c.info.encode(nil); err != nil {
+				return err
+			}
+		}
+
+		if packOrder != c.proto.PackOrder {
+			return errPackOrder
 		}
 
 		if c.info.filePack[0] == protocol.HeaderFileFinishType {
-			c.close <- struct{}{}
+			return errPackOrder
+		}
+
+		err = c.info.SendFile(c.conf.PackSize)
+		if err != nil {
+			return err
 		}
 	}
 }
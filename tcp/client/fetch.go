@@ -0,0 +1,211 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package client
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"io"
+	"net"
+
+	"github.com/TechCatsLab/redalert/codec"
+	"github.com/TechCatsLab/redalert/protocol"
+	"github.com/TechCatsLab/redalert/storage"
+)
+
+var (
+	// ErrHashNotMatch hash of received file not match with server's
+	ErrHashNotMatch = errors.New("Hash value not match")
+
+	errNotFound    = errors.New("File not found on server")
+	errNameTooLong = errors.New("File name too long")
+)
+
+// Fetch download file conf.FileName from server and save it to dst as name.
+// The server sends the file the way a client pushes one.
+func Fetch(conf *Conf, dst storage.Storage, name string) error {
+	conn, err := dial(conf)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if len(conf.FileName) > protocol.FirstPacketSize-protocol.FixedHeaderSize {
+		return errNameTooLong
+	}
+
+	if conf.PackSize < protocol.FirstPacketSize {
+		conf.PackSize = protocol.FirstPacketSize
+	}
+
+	request := protocol.Encode{
+		Body: make([]byte, protocol.FirstPacketSize),
+	}
+	request.Buffer = bytes.NewBuffer(request.Body)
+
+	proto := protocol.Proto{
+		HeaderType: protocol.HeaderGetType,
+		HeaderSize: uint16(protocol.FixedHeaderSize + len(conf.FileName)),
+		PackSize:   uint16(conf.PackSize),
+	}
+	request.Marshal(&proto)
+	copy(request.Body[protocol.FileNameOffset:], conf.FileName)
+
+	var c codec.Codec
+	if conf.Codec != 0 {
+		if c = codec.Get(conf.Codec); c == nil {
+			return errCodec
+		}
+
+		opts := protocol.Options{}
+		opts.SetByte(protocol.OptionCodec, conf.Codec)
+		if err = opts.Marshal(request.Body[proto.HeaderSize:]); err != nil {
+			return err
+		}
+	}
+
+	if _, err = conn.Write(request.Body); err != nil {
+		return err
+	}
+
+	reply, err := readReply(conn, make([]byte, protocol.ReplySize), conf.Key, request.Body)
+	if err != nil {
+		return err
+	}
+
+	switch reply {
+	case 0:
+	case protocol.ReplyNotFound:
+		return errNotFound
+	case protocol.ReplyForbidden:
+		return ErrForbidden
+	case protocol.ReplyBusy:
+		return errBusy
+	case protocol.ReplyUnsupported:
+		return errCodec
+	default:
+		return errFromServer
+	}
+
+	file, err := dst.Create(name)
+	if err != nil {
+		return err
+	}
+
+	if err = receive(conn, file, conf.PackSize, c); err != nil {
+		file.Abort()
+		return err
+	}
+
+	return file.Commit()
+}
+
+// objectWriter write to file sequentially and hash the content
+type objectWriter struct {
+	file   storage.Object
+	offset int64
+	hash   hash.Hash
+}
+
+func (w *objectWriter) Write(p []byte) (int, error) {
+	n, err := w.file.WriteAt(p, w.offset)
+	w.offset += int64(n)
+	w.hash.Write(p[:n])
+
+	return n, err
+}
+
+// receive write packs from conn to file and reply their order, until the
+// finish pack arrives and its hash checked. Packs are a stream compressed by
+// c if it's not nil.
+func receive(conn net.Conn, file storage.Object, packSize int, c codec.Codec) error {
+	pack := protocol.Encode{
+		Body: make([]byte, packSize),
+	}
+	proto := protocol.Proto{}
+	reply := make([]byte, protocol.ReplySize)
+	w := &objectWriter{
+		file: file,
+		hash: md5.New(),
+	}
+
+	var out io.Writer = w
+	var stream io.WriteCloser
+	if c != nil {
+		stream = codec.NewDecompressWriter(c, w)
+		defer stream.Close()
+		out = stream
+	}
+
+	for order := uint32(1); ; order++ {
+		num, err := protocol.ReadPacket(conn, &pack, &proto)
+		if err != nil {
+			return err
+		}
+
+		if proto.PackOrder != order {
+			return errPackOrder
+		}
+
+		body := pack.Body[protocol.FixedHeaderSize:num]
+
+		if proto.HeaderType == protocol.HeaderFileFinishType {
+			if stream != nil {
+				if err = stream.Close(); err != nil {
+					return err
+				}
+			}
+
+			if !bytes.Equal(body, w.hash.Sum(nil)) {
+				binary.BigEndian.PutUint32(reply, protocol.ReplyError)
+				conn.Write(reply)
+
+				return ErrHashNotMatch
+			}
+
+			binary.BigEndian.PutUint32(reply, protocol.ReplyFinish)
+			_, err = conn.Write(reply)
+
+			return err
+		}
+
+		if _, err = out.Write(body); err != nil {
+			return err
+		}
+
+		binary.BigEndian.PutUint32(reply, order)
+		if _, err = conn.Write(reply); err != nil {
+			return err
+		}
+	}
+}
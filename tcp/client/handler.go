@@ -30,7 +30,6 @@
 package client
 
 import (
-	"fmt"
 	"os"
 	"sync"
 )
@@ -51,7 +50,7 @@ type Provider struct {
 func (ph *Provider) OnError(err error) {
 	ph.Lock()
 	defer ph.Unlock()
-	fmt.Printf("[ERROR] client crash with error %v \n", err)
+	ph.client.log.Error("client crash", "err", err)
 	ph.client.info.file.Close()
 	ph.client.conn.Close()
 
@@ -62,7 +61,7 @@ func (ph *Provider) OnError(err error) {
 func (ph *Provider) OnClose() {
 	ph.Lock()
 	defer ph.Unlock()
-	fmt.Println("[WARN] client conn closed")
+	ph.client.log.Info("client conn closed")
 	ph.client.info.file.Close()
 	ph.client.conn.Close()
 
@@ -32,14 +32,18 @@ package client
 import (
 	"bytes"
 	"crypto/md5"
+	"encoding/binary"
+	"encoding/hex"
 	"errors"
-	"fmt"
 	"hash"
 	"io"
 	"os"
 	"strings"
 
+	"github.com/TechCatsLab/redalert/codec"
+	"github.com/TechCatsLab/redalert/delta"
 	"github.com/TechCatsLab/redalert/protocol"
+	"github.com/TechCatsLab/redalert/sparse"
 )
 
 // FileInfo - TCP pack information
@@ -50,14 +54,24 @@ type FileInfo struct {
 	filePack   []byte
 	hash       hash.Hash
 	file       *os.File
+	reader     io.Reader      // content of file to send, see encode
+	codec      codec.Codec    // compress content if not nil
+	sparse     *sparse.Reader // read file by data and holes if not nil, see encode
 	fileInfo   os.FileInfo
 	fileOffset uint32
 }
 
 var (
+	// ErrForbidden error for request denied by policy of server
+	ErrForbidden = errors.New("Request denied by server")
+
 	errInvalidHeaderSize = errors.New("Header size out of range")
 	errFromServer        = errors.New("Got error from server")
 	errPackOrder         = errors.New("Pack order messed")
+	errBusy              = errors.New("Server busy")
+	errCodec             = errors.New("Codec not supported")
+	errRelay             = errors.New("Codec or relay not supported")
+	errNetwork           = errors.New("Network not supported")
 )
 
 func (fi *FileInfo) initFile(name string) error {
@@ -71,18 +85,79 @@ func (fi *FileInfo) initFile(name string) error {
 		return err
 	}
 
-	fmt.Printf("file name is %v size is %v: \n", fileInfo.Name(), fileInfo.Size())
+	fi.client.log.Info("send file", "size", fileInfo.Size())
 	fi.file = file
 	fi.fileInfo = fileInfo
 	fi.hash = md5.New()
 
+	if id := fi.client.conf.Codec; id != 0 {
+		if fi.codec = codec.Get(id); fi.codec == nil {
+			return errCodec
+		}
+	}
+
+	return nil
+}
+
+// encode make the reader of content to send once server accepts the file,
+// content is the delta against sig if sig is not nil, and it's compressed
+// if codec is set. Holes are sent only if neither is used.
+func (fi *FileInfo) encode(sig *delta.Signature) error {
+	if fi.client.conf.Sparse && sig == nil && fi.codec == nil {
+		var err error
+		fi.sparse, err = sparse.NewReader(fi.file)
+
+		return err
+	}
+
+	fi.reader = io.TeeReader(fi.file, fi.hash)
+
+	if sig != nil {
+		fi.reader = delta.NewReader(sig, fi.reader)
+	}
+
+	if fi.codec != nil {
+		fi.reader = codec.NewCompressReader(fi.codec, fi.reader)
+	}
+
 	return nil
 }
 
+// read read next pack of file into p, or the length of hole at the offset
+// if file is read by holes, content is written to hash
+func (fi *FileInfo) read(p []byte) (int, int64, error) {
+	if fi.sparse == nil {
+		n, err := io.ReadFull(fi.reader, p)
+		if err == io.ErrUnexpectedEOF {
+			err = nil
+		}
+
+		return n, 0, err
+	}
+
+	n, hole, err := fi.sparse.Next(p)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	fi.hash.Write(p[:n])
+
+	return n, hole, sparse.WriteZeros(fi.hash, hole)
+}
+
 // first pack which for consult
 func (fi *FileInfo) consult() error {
+	name := fi.client.conf.Dest
+	if name == "" {
+		name = fi.fileInfo.Name()
+	}
+
+	if len(name) > protocol.FirstPacketSize-protocol.FixedHeaderSize {
+		return errNameTooLong
+	}
+
 	fi.client.proto.PackSize = uint16(fi.client.conf.PackSize)
-	fi.client.proto.HeaderSize = uint16(len(fi.fileInfo.Name()) + protocol.FixedHeaderSize)
+	fi.client.proto.HeaderSize = uint16(len(name) + protocol.FixedHeaderSize)
 	fi.headPack = make([]byte, protocol.FirstPacketSize)
 
 	err := fi.packHead(fi.headPack)
@@ -91,19 +166,55 @@ func (fi *FileInfo) consult() error {
 	}
 
 	fi.headPack[0] = byte(protocol.HeaderRequestType)
-	nameReader := strings.NewReader(fi.fileInfo.Name())
+	nameReader := strings.NewReader(name)
 	nameReader.Read(fi.headPack[protocol.FixedHeaderSize:])
 
+	opts := protocol.Options{}
+	opts.SetUint64(protocol.OptionSize, uint64(fi.fileInfo.Size()))
+	if fi.client.conf.Codec != 0 {
+		opts.SetByte(protocol.OptionCodec, fi.client.conf.Codec)
+	}
+	if fi.client.conf.Delta {
+		opts.SetByte(protocol.OptionDelta, 1)
+	}
+	if route := fi.client.conf.Route; route != "" {
+		opts[protocol.OptionRoute] = []byte(route)
+	}
+	if fi.client.conf.SkipSame {
+		sum, err := fileSum(fi.file)
+		if err != nil {
+			return err
+		}
+		opts[protocol.OptionHash] = sum
+	}
+	if err = opts.Marshal(fi.headPack[fi.client.proto.HeaderSize:]); err != nil {
+		return err
+	}
+
 	n, err := fi.client.conn.Write(fi.headPack)
 	if err != nil {
-		fi.client.handle.OnError(err)
+		return err
 	}
 
-	fmt.Printf("[WRITE] write %d byte \n", n)
+	fi.client.log.Debug("send request", "bytes", n)
 
 	return nil
 }
 
+// fileSum return MD5 of file, and seek back to the beginning of file
+func fileSum(file *os.File) ([]byte, error) {
+	hash := md5.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return nil, err
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	return hash.Sum(nil), nil
+}
+
 func (fi *FileInfo) packHead(b []byte) error {
 	if len(b) < protocol.FixedHeaderSize {
 		return errInvalidHeaderSize
@@ -122,16 +233,17 @@ func (fi *FileInfo) packHead(b []byte) error {
 
 // SendFile send file pack by size
 func (fi *FileInfo) SendFile(size int) error {
-	n, err := fi.file.Read(fi.filePack[protocol.FixedHeaderSize:])
+	n, hole, err := fi.read(fi.filePack[protocol.FixedHeaderSize:])
 	if err != nil {
 		if err == io.EOF {
 			hashResult := fi.hash.Sum(nil)
 
-			fmt.Printf("[DEBUG]:Send file finish.hash %x\n", hashResult)
+			fi.client.log.Info("send file finish", "hash", hex.EncodeToString(hashResult))
 
 			reader := bytes.NewReader(hashResult)
 			reader.Read(fi.filePack[protocol.FixedHeaderSize:])
 			fi.filePack[0] = protocol.HeaderFileFinishType
+			binary.BigEndian.PutUint16(fi.filePack[protocol.PackSizeOffset:], md5.Size)
 
 			_, err = fi.client.conn.Write(fi.filePack[:protocol.FixedHeaderSize+16])
 			if err != nil {
@@ -144,12 +256,15 @@ func (fi *FileInfo) SendFile(size int) error {
 		return err
 	}
 
-	_, err = fi.hash.Write(fi.filePack[protocol.FixedHeaderSize:protocol.FixedHeaderSize+n])
-	if err != nil {
-		return err
+	packType := byte(protocol.HeaderFileType)
+	if hole > 0 {
+		packType = protocol.HeaderHoleType
+		n = sparse.HoleSize
+		binary.BigEndian.PutUint64(fi.filePack[protocol.FixedHeaderSize:], uint64(hole))
 	}
 
 	fi.client.proto.PackOrder++
+	fi.client.proto.PackSize = uint16(n)
 
 	filePacket := protocol.Encode{
 		Body: fi.filePack,
@@ -157,9 +272,9 @@ func (fi *FileInfo) SendFile(size int) error {
 	filePacket.Buffer = bytes.NewBuffer(filePacket.Body)
 
 	filePacket.Marshal(fi.client.proto)
-	fi.filePack[0] = protocol.HeaderFileType
+	fi.filePack[0] = packType
 
-	fmt.Printf("[SendFile] send pack order is %v \n", fi.client.proto.PackOrder)
+	fi.client.log.Debug("send pack", "order", fi.client.proto.PackOrder)
 	_, err = fi.client.conn.Write(fi.filePack[:protocol.FixedHeaderSize+n])
 	if err != nil {
 		return err
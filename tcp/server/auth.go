@@ -0,0 +1,84 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package server
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+
+	"github.com/TechCatsLab/redalert/auth"
+	"github.com/TechCatsLab/redalert/protocol"
+)
+
+// authenticate challenge client of request, return ID of client
+func (s *Server) authenticate(conn net.Conn, request []byte) (string, error) {
+	if s.conf.Auth == nil {
+		return "", nil
+	}
+
+	challenge, err := auth.NewChallenge()
+	if err != nil {
+		return "", err
+	}
+
+	reply := make([]byte, protocol.ReplySize+auth.ChallengeSize)
+	binary.BigEndian.PutUint32(reply, protocol.ReplyChallenge)
+	copy(reply[protocol.ReplySize:], challenge)
+
+	if _, err = conn.Write(reply); err != nil {
+		return "", err
+	}
+
+	packet := protocol.Encode{
+		Body: make([]byte, protocol.FixedHeaderSize+1+auth.MaxIDSize+auth.MACSize),
+	}
+	packet.Buffer = bytes.NewBuffer(packet.Body)
+
+	proto := protocol.Proto{}
+	size, err := protocol.ReadPacket(conn, &packet, &proto)
+	if err != nil {
+		return "", err
+	}
+
+	id, mac, err := auth.Parse(packet.Body[:size])
+	if err == nil && !s.conf.Auth.Verify(id, challenge, request, mac) {
+		err = auth.ErrUnauthorized
+	}
+
+	if err != nil {
+		binary.BigEndian.PutUint32(reply, protocol.ReplyUnauthorized)
+		conn.Write(reply[:protocol.ReplySize])
+
+		return "", err
+	}
+
+	return id, nil
+}
@@ -29,9 +29,42 @@
 
 package server
 
+import (
+	"crypto/tls"
+	"net"
+	"time"
+
+	"github.com/TechCatsLab/redalert/auth"
+	"github.com/TechCatsLab/redalert/logger"
+	"github.com/TechCatsLab/redalert/policy"
+	"github.com/TechCatsLab/redalert/storage"
+)
+
 // Conf Tcp server configure
 type Conf struct {
-	Addr    string // Local Addr
-	Port    string // Local Port
-	MaxConn int    // Connection Limit number
+	Addr    string          // Local Addr
+	Port    string          // Local Port
+	MaxConn int             // Connection Limit number, no limit if 0
+	Storage storage.Storage // Where received files go, default to protocol.DefaultDir
+	Export  storage.Source  // Where downloaded files come from, download is disabled if nil
+	TLS     *tls.Config     // Serve TLS if not nil, set ClientAuth for mutual TLS
+	Auth    auth.Keyring    // Clients must authenticate if not nil
+	Policy  *policy.Policy  // What clients may do, everything if nil
+
+	MaxConnPerIP     int           // Connection limit of a source IP, no limit if 0 or on a unix socket
+	ConnRate         float64       // New connections per second of a source IP, no limit if 0 or on a unix socket
+	HandshakeTimeout time.Duration // Deadline of request and auth, default to 10s
+
+	Limit       float64 // Bytes per second of all transfers, no limit if 0
+	ClientLimit float64 // Bytes per second of a client, by ID or source IP, by ID only on a unix socket, no limit if 0
+
+	Relay     bool        // Forward requests with a route to the next hop instead of storing them
+	RelayHops []string    // Next hops as host:port a relay may connect, routes to others are denied
+	RelayTLS  *tls.Config // Connect next hop by TLS if not nil
+	RelayKey  *auth.Key   // Answer auth challenge of next hop for authenticated clients if not nil
+
+	Network  string       // tcp or unix with Addr as path of socket, default to tcp
+	Listener net.Listener // Accept conns on it instead of listening on Network if not nil
+
+	Log logger.Logger // Where messages go, default to logger.Default()
 }
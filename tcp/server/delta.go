@@ -0,0 +1,84 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package server
+
+import (
+	"io"
+
+	"github.com/TechCatsLab/redalert/delta"
+	"github.com/TechCatsLab/redalert/policy"
+	"github.com/TechCatsLab/redalert/storage"
+)
+
+// deltaBase old copy of a file received as delta
+type deltaBase struct {
+	io.ReaderAt
+	io.Closer
+	sig *delta.Signature
+}
+
+// openBase open the old copy of name and compute its signature, it returns
+// nil if there isn't one the client may read
+func (s *Server) openBase(client, name string) *deltaBase {
+	source, ok := s.storage.(storage.Source)
+	if !ok || s.conf.Policy.Check(client, policy.Read, name, -1) != nil {
+		return nil
+	}
+
+	info, err := s.storage.Stat(name)
+	if err != nil {
+		return nil
+	}
+
+	file, err := source.Open(name)
+	if err != nil {
+		return nil
+	}
+
+	reader, ok := file.(io.ReaderAt)
+	if !ok {
+		file.Close()
+		return nil
+	}
+
+	sig, err := delta.NewSignature(io.NewSectionReader(reader, 0, info.Size), delta.BlockSize(info.Size))
+	if err != nil {
+		s.log.Error("signature error", "file", name, "err", err)
+
+		file.Close()
+		return nil
+	}
+
+	return &deltaBase{
+		ReaderAt: reader,
+		Closer:   file,
+		sig:      sig,
+	}
+}
@@ -0,0 +1,169 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package server
+
+import (
+	"math"
+	"net"
+	"sync"
+
+	"github.com/TechCatsLab/redalert/ratelimit"
+)
+
+// sweepSize is the number of source IPs tracked before idle ones are removed
+const sweepSize = 1024
+
+// limiter limits connections of server and of each source IP
+type limiter struct {
+	slots chan struct{} // one for each connection, no limit if nil
+	perIP int
+	rate  float64
+
+	mu      sync.Mutex
+	sources map[string]*source
+}
+
+// source is the state of a source IP
+type source struct {
+	conns  int
+	bucket *ratelimit.Bucket
+}
+
+func newLimiter(conf *Conf) *limiter {
+	l := &limiter{
+		perIP:   conf.MaxConnPerIP,
+		rate:    conf.ConnRate,
+		sources: make(map[string]*source),
+	}
+
+	if conf.MaxConn > 0 {
+		l.slots = make(chan struct{}, conf.MaxConn)
+	}
+
+	return l
+}
+
+// acquire wait for a free connection slot
+func (l *limiter) acquire() {
+	if l.slots != nil {
+		l.slots <- struct{}{}
+	}
+}
+
+// release free a connection slot
+func (l *limiter) release() {
+	if l.slots != nil {
+		<-l.slots
+	}
+}
+
+// admit report if a new connection from addr is within limits of its source
+// IP, leave must be called when an admitted connection closes. Connections
+// without source IP are only limited by MaxConn.
+func (l *limiter) admit(addr net.Addr) bool {
+	if l.perIP <= 0 && l.rate <= 0 || !hasIP(addr) {
+		return true
+	}
+
+	ip := sourceIP(addr)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	src, ok := l.sources[ip]
+	if !ok {
+		if len(l.sources) >= sweepSize {
+			l.sweep()
+		}
+
+		src = &source{}
+		if l.rate > 0 {
+			src.bucket = ratelimit.NewBucket(l.rate, int(math.Ceil(l.rate)))
+		}
+		l.sources[ip] = src
+	}
+
+	if l.perIP > 0 && src.conns >= l.perIP {
+		return false
+	}
+
+	if src.bucket != nil && !src.bucket.Allow() {
+		return false
+	}
+
+	src.conns++
+
+	return true
+}
+
+// leave release a connection admitted for addr
+func (l *limiter) leave(addr net.Addr) {
+	if l.perIP <= 0 && l.rate <= 0 || !hasIP(addr) {
+		return
+	}
+
+	ip := sourceIP(addr)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if src, ok := l.sources[ip]; ok {
+		src.conns--
+	}
+}
+
+// sweep remove sources without connections and with a full bucket, it must
+// be called with mu held
+func (l *limiter) sweep() {
+	for ip, src := range l.sources {
+		if src.conns == 0 && (src.bucket == nil || src.bucket.Full()) {
+			delete(l.sources, ip)
+		}
+	}
+}
+
+// hasIP tell if addr has a source IP, all clients of a unix socket share
+// the same address, so they can't be told apart by it
+func hasIP(addr net.Addr) bool {
+	return addr.Network() != "unix"
+}
+
+func sourceIP(addr net.Addr) string {
+	if tcpAddr, ok := addr.(*net.TCPAddr); ok {
+		return tcpAddr.IP.String()
+	}
+
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+
+	return host
+}
@@ -0,0 +1,165 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package server
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net"
+
+	"github.com/TechCatsLab/redalert/codec"
+	"github.com/TechCatsLab/redalert/logger"
+	"github.com/TechCatsLab/redalert/metrics"
+	"github.com/TechCatsLab/redalert/protocol"
+)
+
+var errReply = errors.New("Reply not match")
+
+// serve send file name from export to conn, it works as the client does
+// when pushing a file, and the conn replies pack order like a server. The
+// file is compressed by c if not nil.
+func (s *Server) serve(conn net.Conn, log logger.Logger, proto *protocol.Proto, name string, c codec.Codec) {
+	defer conn.Close()
+
+	reply := make([]byte, protocol.ReplySize)
+
+	if s.export == nil {
+		log.Warn("download not enabled")
+
+		binary.BigEndian.PutUint32(reply, protocol.ReplyError)
+		conn.Write(reply)
+		return
+	}
+
+	file, err := s.export.Open(name)
+	if err != nil {
+		log.Error("open file error", "err", err)
+
+		binary.BigEndian.PutUint32(reply, protocol.ReplyNotFound)
+		conn.Write(reply)
+		return
+	}
+	defer file.Close()
+
+	log.Info("serve file")
+
+	transfer := metrics.Start(conn.RemoteAddr().Network(), metrics.Send)
+	result := metrics.ResultError
+	defer func() {
+		transfer.Done(result)
+	}()
+
+	if _, err = conn.Write(reply); err != nil {
+		log.Error("conn write error", "err", err)
+		return
+	}
+
+	packSize := int(proto.PackSize)
+	if packSize < protocol.FirstPacketSize {
+		packSize = protocol.FirstPacketSize
+	}
+
+	pack := protocol.Encode{
+		Body: make([]byte, packSize),
+	}
+	pack.Buffer = bytes.NewBuffer(pack.Body)
+
+	hash := md5.New()
+	out := protocol.Proto{
+		HeaderType: protocol.HeaderFileType,
+		HeaderSize: protocol.FixedHeaderSize,
+	}
+
+	var in io.Reader = io.TeeReader(file, io.MultiWriter(hash, transfer))
+	if c != nil {
+		stream := codec.NewCompressReader(c, in)
+		defer stream.Close()
+		in = stream
+	}
+
+	for {
+		n, err := io.ReadFull(in, pack.Body[protocol.FixedHeaderSize:])
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil && err != io.ErrUnexpectedEOF {
+			log.Error("read file error", "err", err)
+			return
+		}
+
+		out.PackOrder++
+		out.PackSize = uint16(n)
+		pack.Marshal(&out)
+
+		if err = exchange(conn, pack.Body[:protocol.FixedHeaderSize+n], reply, out.PackOrder); err != nil {
+			log.Error("send pack error", "order", out.PackOrder, "err", err)
+			return
+		}
+	}
+
+	hashResult := hash.Sum(nil)
+
+	out.HeaderType = protocol.HeaderFileFinishType
+	out.PackOrder++
+	out.PackSize = md5.Size
+	pack.Marshal(&out)
+	copy(pack.Body[protocol.FixedHeaderSize:], hashResult)
+
+	if err = exchange(conn, pack.Body[:protocol.FixedHeaderSize+md5.Size], reply, protocol.ReplyFinish); err != nil {
+		log.Error("send finish error", "err", err)
+		return
+	}
+
+	log.Info("serve file finish", "hash", hex.EncodeToString(hashResult))
+
+	result = metrics.ResultOK
+}
+
+// exchange write a pack to conn and wait for the expected reply
+func exchange(conn net.Conn, pack, reply []byte, expect uint32) error {
+	if _, err := conn.Write(pack); err != nil {
+		return err
+	}
+
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+
+	if binary.BigEndian.Uint32(reply) != expect {
+		return errReply
+	}
+
+	return nil
+}
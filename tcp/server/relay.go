@@ -0,0 +1,207 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package server
+
+import (
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/TechCatsLab/redalert/auth"
+	"github.com/TechCatsLab/redalert/logger"
+	"github.com/TechCatsLab/redalert/metrics"
+	"github.com/TechCatsLab/redalert/protocol"
+)
+
+const (
+	relayDialTimeout = 10 * time.Second
+)
+
+var (
+	// errRelayAuth next hop challenges relay without a key, or for a client
+	// not authenticated
+	errRelayAuth = errors.New("Next hop requires auth")
+	// errRelayHop next hop is not in RelayHops
+	errRelayHop = errors.New("Next hop not permitted")
+)
+
+// relay forward request to the next hop of route, then pipe packets and
+// replies between conn and the next hop until both sides are done. Packets
+// are forwarded as they are, the last hop checks the file by the hash of
+// the client. Only hops in RelayHops are connected.
+func (s *Server) relay(conn net.Conn, log logger.Logger, client string, request []byte, proto *protocol.Proto, route string) {
+	hop, rest := route, ""
+	if i := strings.Index(route, ","); i >= 0 {
+		hop, rest = route[:i], route[i+1:]
+	}
+
+	if !s.hopAllowed(hop) {
+		metrics.Reject(conn.RemoteAddr().Network(), metrics.Receive, metrics.ResultDenied)
+		deny(conn, log.With("hop", hop), errRelayHop)
+		return
+	}
+
+	forward := make([]byte, protocol.FirstPacketSize)
+	copy(forward, request[:proto.HeaderSize])
+
+	opts := protocol.ParseOptions(request[proto.HeaderSize:])
+	delete(opts, protocol.OptionRoute)
+	if rest != "" {
+		opts[protocol.OptionRoute] = []byte(rest)
+	}
+
+	reply := make([]byte, protocol.ReplySize)
+	fail := func(err error) {
+		log.Error("relay error", "hop", hop, "err", err)
+
+		binary.BigEndian.PutUint32(reply, protocol.ReplyError)
+		conn.Write(reply)
+	}
+
+	if err := opts.Marshal(forward[proto.HeaderSize:]); err != nil {
+		fail(err)
+		return
+	}
+
+	next, err := s.dialHop(hop)
+	if err != nil {
+		fail(err)
+		return
+	}
+	defer next.Close()
+
+	if _, err = next.Write(forward); err != nil {
+		fail(err)
+		return
+	}
+
+	// the reply of next hop to request, or it fails authentication
+	if err = s.answer(next, client, reply, forward); err != nil {
+		fail(err)
+		return
+	}
+
+	log.Info("relay file", "hop", hop)
+
+	if _, err = conn.Write(reply); err != nil {
+		return
+	}
+
+	conn.SetDeadline(time.Time{})
+	next.SetDeadline(time.Time{})
+
+	done := make(chan struct{})
+	go func() {
+		io.Copy(conn, next)
+		close(done)
+	}()
+
+	io.Copy(next, conn)
+	if cw, ok := next.(interface{ CloseWrite() error }); ok {
+		cw.CloseWrite()
+	}
+	<-done
+}
+
+// hopAllowed tell if hop is one of RelayHops
+func (s *Server) hopAllowed(hop string) bool {
+	host, port, err := net.SplitHostPort(hop)
+	if err != nil {
+		return false
+	}
+
+	for _, allowed := range s.conf.RelayHops {
+		h, p, err := net.SplitHostPort(allowed)
+		if err == nil && p == port && strings.EqualFold(h, host) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// dialHop connect to next hop, by TLS if RelayTLS is set
+func (s *Server) dialHop(hop string) (net.Conn, error) {
+	conn, err := net.DialTimeout("tcp", hop, relayDialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(relayDialTimeout))
+
+	if s.conf.RelayTLS == nil {
+		return conn, nil
+	}
+
+	conf := s.conf.RelayTLS
+	if conf.ServerName == "" {
+		conf = conf.Clone()
+		conf.ServerName, _, _ = net.SplitHostPort(hop)
+	}
+
+	tlsConn := tls.Client(conn, conf)
+	if err = tlsConn.Handshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return tlsConn, nil
+}
+
+// answer read reply of next hop into reply, auth challenge is answered
+// by RelayKey, only for an authenticated client so the relay doesn't lend
+// its identity to anyone
+func (s *Server) answer(next net.Conn, client string, reply, request []byte) error {
+	for {
+		if _, err := io.ReadFull(next, reply); err != nil {
+			return err
+		}
+
+		if binary.BigEndian.Uint32(reply) != protocol.ReplyChallenge {
+			return nil
+		}
+
+		if s.conf.RelayKey == nil || client == "" {
+			return errRelayAuth
+		}
+
+		challenge := make([]byte, auth.ChallengeSize)
+		if _, err := io.ReadFull(next, challenge); err != nil {
+			return err
+		}
+
+		if _, err := next.Write(auth.Marshal(s.conf.RelayKey, challenge, request)); err != nil {
+			return err
+		}
+	}
+}
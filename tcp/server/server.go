@@ -32,74 +32,180 @@ package server
 import (
 	"bytes"
 	"crypto/md5"
-	"log"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"io"
 	"net"
 	"os"
 	"time"
 
+	"github.com/TechCatsLab/redalert/auth"
+	"github.com/TechCatsLab/redalert/codec"
+	"github.com/TechCatsLab/redalert/logger"
+	"github.com/TechCatsLab/redalert/metrics"
+	"github.com/TechCatsLab/redalert/policy"
 	"github.com/TechCatsLab/redalert/protocol"
+	"github.com/TechCatsLab/redalert/ratelimit"
+	"github.com/TechCatsLab/redalert/storage"
 )
 
+const (
+	defaultHandshakeTimeout = 10 * time.Second
+	// busyTimeout bounds the write of busy reply to a connection not admitted
+	busyTimeout = time.Second
+)
+
+var errNetwork = errors.New("Network not supported")
+
 // Server tcp server
 type Server struct {
-	conf      *Conf
-	totalConn int
-	CountChan chan bool
-	listener  *net.TCPListener
+	conf     *Conf
+	limiter  *limiter
+	limit    *ratelimit.Bucket
+	clients  *ratelimit.Group
+	listener net.Listener
+	storage  storage.Storage
+	index    *storage.Index
+	export   storage.Source
+	log      logger.Logger
 }
 
-// NewServer start a new TCP server
+// NewServer start a new TCP server, the process exits on error
 func NewServer(conf *Conf) *Server {
-	tcpAddr, err := net.ResolveTCPAddr("tcp", conf.Addr+":"+conf.Port)
+	s, err := Listen(conf)
 	if err != nil {
-		log.Fatalln("[ERROR]:Can't resolve address:", err)
+		logger.Or(conf.Log).Error("can't listen", "err", err)
+		os.Exit(1)
 	}
 
-	listener, err := net.ListenTCP("tcp", tcpAddr)
+	return s
+}
+
+// Listen start a new TCP server listening on conf.Addr and conf.Port, or a
+// unix socket server on path conf.Addr
+func Listen(conf *Conf) (*Server, error) {
+	ln, err := listen(conf)
 	if err != nil {
-		log.Fatalln("[ERROR]:Can't resolve address:", err)
+		return nil, err
 	}
 
-	log.Printf("[server] start at %s", tcpAddr.String())
+	if conf.TLS != nil {
+		ln = tls.NewListener(ln, conf.TLS)
+	}
 
 	s := &Server{
-		conf:      conf,
-		totalConn: 0,
-		CountChan: make(chan bool),
-		listener:  listener,
+		conf:     conf,
+		limiter:  newLimiter(conf),
+		limit:    ratelimit.NewByteBucket(conf.Limit),
+		clients:  ratelimit.NewGroup(conf.ClientLimit),
+		listener: ln,
+		storage:  conf.Storage,
+		export:   conf.Export,
+		log:      logger.Or(conf.Log),
 	}
 
-	return s
+	s.log.Info("server started", "addr", ln.Addr().String())
+
+	if s.storage == nil {
+		s.storage = storage.NewLocal(protocol.DefaultDir)
+	}
+	s.index = storage.NewIndex(s.storage)
+
+	return s, nil
 }
 
-// Start TCP server
-func (s *Server) Start() {
-	countConn(s)
+// listen open listener of conf.Network, a stale unix socket is removed
+func listen(conf *Conf) (net.Listener, error) {
+	if conf.Listener != nil {
+		return conf.Listener, nil
+	}
 
+	switch conf.Network {
+	case "", "tcp":
+	case "unix":
+		if info, err := os.Lstat(conf.Addr); err == nil && info.Mode()&os.ModeSocket != 0 {
+			os.Remove(conf.Addr)
+		}
+
+		return net.Listen("unix", conf.Addr)
+	default:
+		return nil, errNetwork
+	}
+
+	tcpAddr, err := net.ResolveTCPAddr("tcp", net.JoinHostPort(conf.Addr, conf.Port))
+	if err != nil {
+		return nil, err
+	}
+
+	return net.ListenTCP("tcp", tcpAddr)
+}
+
+// Start TCP server, a connection is accepted once there are less than
+// MaxConn connections, it returns once the listener is closed
+func (s *Server) Start() {
 	for {
-		if s.totalConn >= s.conf.MaxConn {
-			time.Sleep(time.Second * 2)
-		} else {
-			conn, err := s.listener.AcceptTCP()
-			if err != nil {
-				log.Println("[ERROR]:listen error", err)
-			} else {
-				s.onConn(conn)
-			}
+		s.limiter.acquire()
+
+		conn, err := s.listener.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			s.limiter.release()
+			return
+		}
+		if err != nil {
+			s.log.Error("accept error", "err", err)
+
+			s.limiter.release()
+			time.Sleep(100 * time.Millisecond)
+			continue
 		}
+
+		go s.onConn(conn)
 	}
 }
 
-func (s *Server) onConn(conn *net.TCPConn) {
+// onConn handshake with conn and serve it, the handshake must finish in
+// HandshakeTimeout. Limits of source IP are checked before anything is read,
+// so idle connections of one source can't hold every slot.
+func (s *Server) onConn(conn net.Conn) {
+	defer func() {
+		conn.Close()
+		s.limiter.release()
+	}()
+
+	timeout := s.conf.HandshakeTimeout
+	if timeout <= 0 {
+		timeout = defaultHandshakeTimeout
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	network := conn.RemoteAddr().Network()
+	log := s.log.With("remote", conn.RemoteAddr().String())
+
+	if !s.limiter.admit(conn.RemoteAddr()) {
+		log.Warn("too many connections")
+
+		reply := make([]byte, protocol.ReplySize)
+		binary.BigEndian.PutUint32(reply, protocol.ReplyBusy)
+		conn.SetDeadline(time.Now().Add(busyTimeout))
+		conn.Write(reply)
+		return
+	}
+	defer s.limiter.leave(conn.RemoteAddr())
+
 	firstDecode := protocol.Encode{
 		Body: make([]byte, protocol.FirstPacketSize),
 	}
 
 	firstDecode.Buffer = bytes.NewBuffer(firstDecode.Body)
 
-	_, err := conn.Read(firstDecode.Body)
+	_, err := io.ReadFull(conn, firstDecode.Body)
 	if err != nil {
-		log.Println("[ERROR]:Conn read error", err)
+		log.Error("conn read error", "err", err)
+
+		if result(err) == metrics.ResultTimeout {
+			metrics.Timeouts.With(network).Inc()
+		}
 		return
 	}
 
@@ -107,17 +213,132 @@ func (s *Server) onConn(conn *net.TCPConn) {
 
 	firstDecode.Unmarshal(&proto)
 
-	log.Printf("[CONN]:Begin create file, Proto: %#v\n", proto)
+	if proto.HeaderSize < protocol.FixedHeaderSize || int(proto.HeaderSize) > len(firstDecode.Body) {
+		log.Error("invalid header size", "size", proto.HeaderSize)
+		return
+	}
+
+	direction := metrics.Receive
+	if proto.HeaderType == protocol.HeaderGetType {
+		direction = metrics.Send
+	}
+
+	client, err := s.authenticate(conn, firstDecode.Body)
+	if err != nil {
+		log.Error("authenticate error", "err", err)
+
+		metrics.Reject(network, direction, result(err))
+		return
+	}
 
 	filename := string(firstDecode.Body[protocol.FileNameOffset:proto.HeaderSize])
+	log = log.With("file", filename)
+	if client != "" {
+		log = log.With("client", client)
+	}
+
+	// clients of a unix socket are limited by ID only
+	var bucket *ratelimit.Bucket
+	switch {
+	case client != "":
+		bucket = s.clients.Get(client)
+	case hasIP(conn.RemoteAddr()):
+		bucket = s.clients.Get(sourceIP(conn.RemoteAddr()))
+	}
+	conn = ratelimit.NewConn(conn, s.limit, bucket)
+
+	opts := protocol.ParseOptions(firstDecode.Body[proto.HeaderSize:])
+
+	var c codec.Codec
+	if id, ok := opts.Byte(protocol.OptionCodec); ok {
+		if c = codec.Get(id); c == nil {
+			log.Error("codec not supported", "codec", id)
 
-	file, err := os.Create(protocol.DefaultDir + filename)
+			reply := make([]byte, protocol.ReplySize)
+			binary.BigEndian.PutUint32(reply, protocol.ReplyUnsupported)
+			conn.Write(reply)
+			return
+		}
+	}
+
+	if proto.HeaderType == protocol.HeaderGetType {
+		if err = s.conf.Policy.Check(client, policy.Read, filename, -1); err != nil {
+			metrics.Reject(network, direction, metrics.ResultDenied)
+			deny(conn, log, err)
+			return
+		}
+
+		conn.SetDeadline(time.Time{})
+		s.serve(conn, log, &proto, filename, c)
+		return
+	}
+
+	// packs are read into a buffer of PackSize, it must hold a finish pack
+	if proto.PackSize < protocol.FixedHeaderSize+md5.Size || proto.PackSize > protocol.MaxPacketSize {
+		log.Error("invalid pack size", "size", proto.PackSize)
+
+		reply := make([]byte, protocol.ReplySize)
+		binary.BigEndian.PutUint32(reply, protocol.ReplyError)
+		conn.Write(reply)
+		return
+	}
+
+	size := int64(-1)
+	if v, ok := opts.Uint64(protocol.OptionSize); ok {
+		size = int64(v)
+	}
+
+	if route, ok := opts[protocol.OptionRoute]; ok {
+		if !s.conf.Relay {
+			log.Error("not a relay", "route", string(route))
+
+			reply := make([]byte, protocol.ReplySize)
+			binary.BigEndian.PutUint32(reply, protocol.ReplyUnsupported)
+			conn.Write(reply)
+			return
+		}
+
+		if err = s.conf.Policy.Check(client, policy.Relay, filename, size); err != nil {
+			metrics.Reject(network, direction, metrics.ResultDenied)
+			deny(conn, log, err)
+			return
+		}
+
+		s.relay(conn, log, client, firstDecode.Body, &proto, string(route))
+		return
+	}
+
+	if err = s.conf.Policy.Check(client, policy.Write, filename, size); err != nil {
+		metrics.Reject(network, direction, metrics.ResultDenied)
+		deny(conn, log, err)
+		return
+	}
+
+	if sum, ok := opts[protocol.OptionHash]; ok && s.present(client, filename, sum, size) {
+		log.Info("file is present")
+
+		reply := make([]byte, protocol.ReplySize)
+		binary.BigEndian.PutUint32(reply, protocol.ReplyPresent)
+		conn.Write(reply)
+		return
+	}
+
+	var base *deltaBase
+	if _, ok := opts.Byte(protocol.OptionDelta); ok {
+		conn.SetDeadline(time.Time{})
+
+		if base = s.openBase(client, filename); base != nil {
+			defer base.Close()
+		}
+	}
+
+	file, err := s.storage.Create(filename)
 	if err != nil {
-		log.Println("[ERROR]:Create file error", err)
+		log.Error("create file error", "err", err)
 		return
 	}
 
-	log.Printf("[DEBUG]:File name %s", filename)
+	log.Info("receive file", "codec", c != nil, "delta", base != nil)
 
 	decode := protocol.Encode{
 		Body: make([]byte, proto.PackSize),
@@ -125,40 +346,86 @@ func (s *Server) onConn(conn *net.TCPConn) {
 
 	decode.Buffer = bytes.NewBuffer(decode.Body)
 
+	transfer := metrics.Start(network, direction)
 	session := Session{
-		Pack:      &decode,
-		Reply:     make([]byte, protocol.ReplySize),
-		file:      file,
-		conn:      conn,
-		proto:     &proto,
-		CountChan: s.CountChan,
-		hash:      md5.New(),
+		Pack:  &decode,
+		Reply: make([]byte, protocol.ReplySize),
+		file: &objectWriter{
+			Object: file,
+			hash:   md5.New(),
+			limit:  s.conf.Policy.MaxSize(client),
+			size:   size,
+			bytes:  transfer,
+		},
+		conn:  conn,
+		proto: &proto,
+		codec: c,
+		base:  base,
+		name:  filename,
+		index: s.index,
+		log:   log,
+
+		transfer: transfer,
+	}
+
+	if base != nil {
+		binary.BigEndian.PutUint32(session.Reply, protocol.ReplySignature)
 	}
 
 	num, err := conn.Write(session.Reply)
+	if err == nil && base != nil {
+		log.Debug("send signature", "blocks", len(base.sig.Blocks))
+
+		_, err = base.sig.WriteTo(conn)
+	}
 	if err != nil {
-		log.Printf("[ERROR]:Conn write %d word, error %v", num, err)
+		log.Error("conn write error", "bytes", num, "err", err)
 
+		file.Abort()
+		transfer.Done(metrics.ResultError)
 		return
 	}
 
-	s.CountChan <- true
-	go session.Start()
+	conn.SetDeadline(time.Time{})
+	session.Start()
 }
 
-func countConn(s *Server) {
-	go func() {
-		for {
-			count := <-s.CountChan
-			if count {
-				s.totalConn++
-			} else {
-				s.totalConn--
-			}
-
-			//if s.totalConn == 0 {
-			//	os.Exit(0)
-			//}
-		}
-	}()
+// present tell if name has content of sum and size, or it's copied from
+// another file, the client must be permitted to read both files
+func (s *Server) present(client, name string, sum []byte, size int64) bool {
+	if len(sum) != md5.Size || size < 0 || s.conf.Policy.Check(client, policy.Read, name, -1) != nil {
+		return false
+	}
+
+	d := storage.Digest{Size: size}
+	copy(d.Sum[:], sum)
+
+	return s.index.Ensure(name, d, func(source string) bool {
+		return s.conf.Policy.Check(client, policy.Read, source, -1) == nil
+	})
+}
+
+// result return result of a transfer failed with err in metrics
+func result(err error) string {
+	var netErr net.Error
+
+	switch {
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return metrics.ResultTimeout
+	case policy.Denied(err):
+		return metrics.ResultDenied
+	case err == auth.ErrUnauthorized, err == auth.ErrInvalidPacket:
+		return metrics.ResultUnauthorized
+	}
+
+	return metrics.ResultError
+}
+
+// deny reply request denied by policy
+func deny(conn net.Conn, log logger.Logger, err error) {
+	log.Warn("deny request", "err", err)
+
+	reply := make([]byte, protocol.ReplySize)
+	binary.BigEndian.PutUint32(reply, protocol.ReplyForbidden)
+	conn.Write(reply)
 }
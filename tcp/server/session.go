@@ -30,100 +30,254 @@
 package server
 
 import (
-	"encoding/binary"
 	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
 	"hash"
-	"log"
+	"io"
 	"net"
-	"os"
 
+	"github.com/TechCatsLab/redalert/codec"
+	"github.com/TechCatsLab/redalert/delta"
+	"github.com/TechCatsLab/redalert/logger"
+	"github.com/TechCatsLab/redalert/metrics"
+	"github.com/TechCatsLab/redalert/policy"
 	"github.com/TechCatsLab/redalert/protocol"
+	"github.com/TechCatsLab/redalert/sparse"
+	"github.com/TechCatsLab/redalert/storage"
 )
 
+var errInvalidHole = errors.New("Invalid hole packet")
+
 // Session a connection
 type Session struct {
-	Pack      *protocol.Encode
-	Reply     []byte
-	file      *os.File
-	conn      net.Conn
-	proto     *protocol.Proto
-	CountChan chan bool
-	hash      hash.Hash
+	Pack   *protocol.Encode
+	Reply  []byte
+	file   *objectWriter
+	conn   net.Conn
+	proto  *protocol.Proto
+	codec  codec.Codec    // codec of stream, nil if not compressed
+	stream io.WriteCloser // decompress stream to patch or file
+	base   *deltaBase     // old copy of file if stream is a delta
+	patch  *delta.Patcher // apply delta to file
+	name   string
+	index  *storage.Index
+	log    logger.Logger // with fields of the session
+
+	transfer *metrics.Transfer // count file received in metrics
+}
+
+// objectWriter write to file sequentially and hash the content, it stops at
+// limit
+type objectWriter struct {
+	storage.Object
+	offset int64
+	hash   hash.Hash
+	limit  int64             // max size of file, no limit if 0
+	size   int64             // size of file declared by request, -1 if unknown
+	bytes  *metrics.Transfer // count bytes written in metrics if not nil
+}
+
+// skip make n bytes of zeros at offset, a hole if the object supports it,
+// the hole must end within the declared size
+func (w *objectWriter) skip(n int64) error {
+	if n < 0 || w.size < 0 || n > w.size-w.offset {
+		return errInvalidHole
+	}
+	if w.limit > 0 && w.offset+n > w.limit {
+		return policy.ErrTooLarge
+	}
+
+	if err := storage.Hole(w.Object, w.offset, n); err != nil {
+		return err
+	}
+	w.offset += n
+
+	return sparse.WriteZeros(w.hash, n)
+}
+
+func (w *objectWriter) Write(p []byte) (int, error) {
+	if w.limit > 0 && w.offset+int64(len(p)) > w.limit {
+		return 0, policy.ErrTooLarge
+	}
+
+	n, err := w.WriteAt(p, w.offset)
+	w.offset += int64(n)
+	w.hash.Write(p[:n])
+	w.bytes.Add(n)
+
+	return n, err
 }
 
 // Start start write file
 func (s *Session) Start() {
+	var out io.Writer = s.file
+	if s.base != nil {
+		s.patch = delta.NewPatcher(s.base.sig, s.base, s.file)
+		out = s.patch
+	}
+	if s.codec != nil {
+		s.stream = codec.NewDecompressWriter(s.codec, out)
+		out = s.stream
+	}
+
+	result := metrics.ResultError
+	defer func() {
+		s.transfer.Done(result)
+	}()
+
 	packOrder := uint32(1)
 	for {
-		num, err := s.conn.Read(s.Pack.Body)
+		num, err := protocol.ReadPacket(s.conn, s.Pack, s.proto)
 		if err != nil {
-			log.Println("[ERROR]:Read connect error", err)
+			s.log.Error("conn read error", "err", err)
 
-			s.file.Close()
-			os.Remove(s.file.Name())
-			s.CountChan <- false
+			s.abort()
 			return
 		}
 
-		log.Printf("[DEBUG]:Read %d bytes.", num)
-
-		s.Pack.Buffer = bytes.NewBuffer(s.Pack.Body)
-		s.Pack.Unmarshal(s.proto)
+		s.log.Debug("read pack", "bytes", num)
 
 		if s.Pack.Body[0] == protocol.HeaderFileFinishType {
-			md5hash := s.hash.Sum(nil)
-			if string(md5hash) != string(s.Pack.Body[protocol.FixedHeaderSize:protocol.FixedHeaderSize+16]) {
-				log.Println("[DEBUG]:MD5 error.")
+			if err = s.close(); err != nil {
+				s.log.Error("decode error", "err", err)
+
+				if policy.Denied(err) {
+					result = metrics.ResultDenied
+				}
+				s.deny(err)
+				s.abort()
+				return
+			}
+
+			if s.patch != nil {
+				s.log.Debug("reuse old file", "bytes", s.patch.Reused())
+			}
 
-				s.file.Close()
-				os.Remove(s.file.Name())
+			md5hash := s.file.hash.Sum(nil)
+			if !bytes.Equal(md5hash, s.Pack.Body[protocol.FixedHeaderSize:num]) {
+				s.log.Error("hash not match")
+
+				result = metrics.ResultHashMismatch
+
+				s.reply(protocol.ReplyError)
+				s.abort()
+				return
 			} else {
-				log.Printf("[DEBUG]:Recive file finish.hash %x", md5hash)
+				s.log.Info("receive file finish", "size", s.file.offset, "hash", hex.EncodeToString(md5hash))
+
+				if err = s.file.Commit(); err != nil {
+					s.log.Error("commit file error", "err", err)
 
-				s.file.Close()
-				s.CountChan <- false
+					s.reply(protocol.ReplyError)
+					return
+				}
+
+				d := storage.Digest{Size: s.file.offset}
+				copy(d.Sum[:], md5hash)
+				s.index.Add(s.name, d)
+
+				result = metrics.ResultOK
+				s.reply(protocol.ReplyFinish)
 				return
 			}
 		}
 
-		log.Println("[DEBUG]:Before judge order", s.proto.PackOrder)
-
 		if s.proto.PackOrder != packOrder {
 			binary.BigEndian.PutUint32(s.Reply, packOrder)
 			_, err := s.conn.Write(s.Reply)
 			if err != nil {
-				log.Println("[ERROR]:Conn write error", err)
-
-				s.file.Close()
-				os.Remove(s.file.Name())
-				s.CountChan <- false
+				s.log.Error("conn write error", "err", err)
 
+				s.abort()
 				return
 			}
 
-			log.Println("[DEBUG]:Resend order", s.proto.PackOrder)
+			s.log.Debug("ask resend", "order", packOrder, "got", s.proto.PackOrder)
 			continue
 		}
 
-		log.Printf("[DEBUG]:PackSize %d, Pack length %d", s.proto.PackSize, len(s.Pack.Body))
 		realBody := s.Pack.Body[protocol.FixedHeaderSize:num]
 
-		s.file.Write(realBody)
-		s.hash.Write(realBody)
+		if s.Pack.Body[0] == protocol.HeaderHoleType {
+			err = s.hole(realBody)
+		} else {
+			_, err = out.Write(realBody)
+		}
+		if err != nil {
+			s.log.Error("write file error", "err", err)
+
+			if policy.Denied(err) {
+				result = metrics.ResultDenied
+			}
+			s.deny(err)
+			s.abort()
+			return
+		}
 
 		binary.BigEndian.PutUint32(s.Reply, packOrder)
 		_, err = s.conn.Write(s.Reply)
 		if err != nil {
-			log.Println("[ERROR]:Conn write error", err)
-
-			s.file.Close()
-			os.Remove(s.file.Name())
-			s.CountChan <- false
+			s.log.Error("conn write error", "err", err)
 
+			s.abort()
 			return
 		}
 
-		log.Printf("[DEBUG]:Handler %d pack.", packOrder)
+		s.log.Debug("handle pack", "order", packOrder)
 		packOrder++
 	}
 }
+
+// hole skip a hole of file, body is the length of hole, holes are only
+// sent if the stream is neither compressed nor a delta
+func (s *Session) hole(body []byte) error {
+	if len(body) != sparse.HoleSize || s.stream != nil || s.patch != nil {
+		return errInvalidHole
+	}
+
+	return s.file.skip(int64(binary.BigEndian.Uint64(body)))
+}
+
+// reply tell client the result of file once its hash is checked, so a
+// client knows the file is kept only when it gets ReplyFinish
+func (s *Session) reply(code uint32) {
+	binary.BigEndian.PutUint32(s.Reply, code)
+	if _, err := s.conn.Write(s.Reply); err != nil {
+		s.log.Error("conn write error", "err", err)
+	}
+}
+
+// deny reply client if err is file exceeds max size
+func (s *Session) deny(err error) {
+	if err == policy.ErrTooLarge {
+		s.log.Warn("file exceeds max size", "limit", s.file.limit)
+
+		binary.BigEndian.PutUint32(s.Reply, protocol.ReplyForbidden)
+		s.conn.Write(s.Reply)
+	}
+}
+
+// close end decompressing and patching, and return the first error
+func (s *Session) close() error {
+	var err error
+	if s.stream != nil {
+		err = s.stream.Close()
+	}
+
+	if s.patch != nil {
+		if e := s.patch.Close(); err == nil {
+			err = e
+		}
+	}
+
+	return err
+}
+
+// abort discard the received part of file and close the connection
+func (s *Session) abort() {
+	s.close()
+	s.file.Abort()
+	s.conn.Close()
+}
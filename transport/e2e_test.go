@@ -0,0 +1,265 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package transport_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/TechCatsLab/redalert/logger"
+	"github.com/TechCatsLab/redalert/memnet"
+	"github.com/TechCatsLab/redalert/storage"
+	"github.com/TechCatsLab/redalert/transport"
+)
+
+const (
+	// server is address of server on the simulated network
+	server = "server:17120"
+
+	sampleSize  = 256 << 10
+	caseTimeout = time.Minute
+)
+
+// link loses, duplicates, reorders and delays packets, streams are reliable
+// and only delayed, packets are corrupted only for encrypted cases
+var link = memnet.Link{
+	Loss:      0.05,
+	Duplicate: 0.02,
+	Reorder:   0.02,
+	Corrupt:   0.01,
+	Latency:   time.Millisecond,
+	Jitter:    time.Millisecond,
+	Seed:      1,
+}
+
+// e2eCase is a transfer through a simulated network
+type e2eCase struct {
+	name   string
+	conf   transport.Conf
+	secure bool // server rejects packets not encrypted, the link corrupts packets
+	sparse bool // file has holes
+	delta  bool // server has an old copy of file
+	get    bool // download file instead of sending it
+}
+
+func TestMain(m *testing.M) {
+	logger.SetDefault(logger.Discard)
+
+	os.Exit(m.Run())
+}
+
+// runCases run cases of transport name, each through a new network
+func runCases(t *testing.T, name string, cases []e2eCase) {
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			runCase(t, name, c)
+		})
+	}
+}
+
+// runCase transfer a file by c and check it arrives byte for byte
+func runCase(t *testing.T, name string, c e2eCase) {
+	file := filepath.Join(t.TempDir(), "a.bin")
+	content := writeSample(t, file, c.sparse)
+
+	l := link
+	if !c.secure {
+		l.Corrupt = 0
+	}
+	network := memnet.New(l)
+
+	srv := storage.NewMemory()
+	if c.delta || c.get {
+		putFile(t, srv, "a.bin", content)
+	}
+	if c.delta {
+		// the new copy differs by a block in the middle
+		content[len(content)/2] ^= 0xff
+		if err := os.WriteFile(file, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	serve(t, name, network, &transport.ServerConf{
+		Addr:    server,
+		Storage: srv,
+		Export:  srv,
+		Secure:  c.secure,
+	})
+
+	conf := c.conf
+	conf.Addr = server
+	conf.Dialer = dialer(name, network)
+
+	done := make(chan error, 1)
+	got := storage.NewMemory()
+	go func() {
+		tr := transport.Get(name)
+		if c.get {
+			done <- tr.Fetch("a.bin", got, "a.bin", &conf)
+		} else {
+			done <- tr.Send(file, &conf)
+		}
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(caseTimeout):
+		t.Fatal("transfer timed out")
+	}
+
+	if !c.get {
+		got = srv
+	}
+
+	// a udp server commits file once its finish is received
+	for wait := 0; ; wait++ {
+		data, ok := got.Bytes("a.bin")
+		if ok && bytes.Equal(data, content) {
+			return
+		}
+		if wait == 20 {
+			t.Fatal("content not match")
+		}
+
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// isStream tell if transport name runs over streams
+func isStream(name string) bool {
+	return name == transport.TCP || name == transport.WS
+}
+
+// serve start a server of transport name on network, it stops at the end of
+// the test
+func serve(t *testing.T, name string, network *memnet.Network, conf *transport.ServerConf) {
+	var closer io.Closer
+	if isStream(name) {
+		l, err := network.Listen(server)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conf.Listener, closer = l, l
+	} else {
+		pc, err := network.ListenPacket(server)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conf.PacketConn, closer = pc, pc
+	}
+	t.Cleanup(func() { closer.Close() })
+
+	go transport.Get(name).Serve(conf)
+}
+
+// dialer return how a client of transport name dials server on network
+func dialer(name string, network *memnet.Network) func() (net.Conn, error) {
+	if isStream(name) {
+		return func() (net.Conn, error) {
+			conn, err := network.Dial(server)
+			if err != nil {
+				return nil, err
+			}
+
+			return conn, nil
+		}
+	}
+
+	return func() (net.Conn, error) {
+		conn, err := network.DialPacket(server)
+		if err != nil {
+			return nil, err
+		}
+
+		return conn, nil
+	}
+}
+
+// writeSample write random content to file and return it, a sparse file has
+// a hole in the middle
+func writeSample(t *testing.T, file string, sparse bool) []byte {
+	content := make([]byte, sampleSize)
+	if _, err := rand.Read(content); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Create(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if !sparse {
+		if _, err = f.Write(content); err != nil {
+			t.Fatal(err)
+		}
+		return content
+	}
+
+	quarter := int64(sampleSize / 4)
+	for i := quarter; i < 3*quarter; i++ {
+		content[i] = 0
+	}
+
+	if _, err = f.WriteAt(content[:quarter], 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = f.WriteAt(content[3*quarter:], 3*quarter); err != nil {
+		t.Fatal(err)
+	}
+
+	return content
+}
+
+// putFile keep content as name in s
+func putFile(t *testing.T, s storage.Storage, name string, content []byte) {
+	o, err := s.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = o.WriteAt(content, 0); err != nil {
+		o.Abort()
+		t.Fatal(err)
+	}
+
+	if err = o.Commit(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,94 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package transport
+
+import (
+	"errors"
+	"net"
+	"os"
+
+	"github.com/TechCatsLab/redalert/auth"
+	"github.com/TechCatsLab/redalert/metrics"
+	"github.com/TechCatsLab/redalert/policy"
+	"github.com/TechCatsLab/redalert/storage"
+	tcpclient "github.com/TechCatsLab/redalert/tcp/client"
+	udpclient "github.com/TechCatsLab/redalert/udp/client"
+)
+
+// trackSend count sending file over network in metrics, fn sends it
+func trackSend(network, file string, fn func() error) error {
+	t := metrics.Start(network, metrics.Send)
+
+	err := fn()
+	if err == nil {
+		if info, e := os.Stat(file); e == nil {
+			t.Add(int(info.Size()))
+		}
+	}
+	t.Done(result(err))
+
+	return err
+}
+
+// trackFetch count downloading file local of dst over network in metrics,
+// fn downloads it
+func trackFetch(network string, dst storage.Storage, local string, fn func() error) error {
+	t := metrics.Start(network, metrics.Receive)
+
+	err := fn()
+	if err == nil {
+		if info, e := dst.Stat(local); e == nil {
+			t.Add(int(info.Size))
+		}
+	}
+	t.Done(result(err))
+
+	return err
+}
+
+// result return result of a transfer failed with err in metrics
+func result(err error) string {
+	var netErr net.Error
+
+	switch {
+	case err == nil:
+		return metrics.ResultOK
+	case errors.Is(err, tcpclient.ErrHashNotMatch), errors.Is(err, udpclient.ErrHashNotMatch):
+		return metrics.ResultHashMismatch
+	case errors.Is(err, udpclient.ErrTimeout), errors.As(err, &netErr) && netErr.Timeout():
+		return metrics.ResultTimeout
+	case errors.Is(err, tcpclient.ErrForbidden), errors.Is(err, udpclient.ErrForbidden), policy.Denied(err):
+		return metrics.ResultDenied
+	case errors.Is(err, auth.ErrUnauthorized), errors.Is(err, auth.ErrRequired):
+		return metrics.ResultUnauthorized
+	}
+
+	return metrics.ResultError
+}
@@ -0,0 +1,144 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package transport
+
+import (
+	"net"
+
+	"github.com/TechCatsLab/redalert/storage"
+	"github.com/TechCatsLab/redalert/tcp/client"
+	"github.com/TechCatsLab/redalert/tcp/server"
+)
+
+// tcpTransport transfer files by tcp/client and tcp/server over network
+type tcpTransport struct {
+	network string
+}
+
+// clientConf convert conf to tcp client conf of file
+func (t tcpTransport) clientConf(file string, conf *Conf) (*client.Conf, error) {
+	host, port, err := t.split(conf.Addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &client.Conf{
+		Address:  host,
+		Port:     port,
+		FileName: file,
+		PackSize: conf.PackSize,
+		TLS:      conf.TLS,
+		Key:      conf.Key,
+		Dest:     conf.Dest,
+		Limit:    conf.Limit,
+		Codec:    conf.Codec,
+		Delta:    conf.Delta,
+		SkipSame: conf.SkipSame,
+		Sparse:   conf.Sparse,
+		Route:    conf.Route,
+		Network:  t.network,
+		Dialer:   conf.Dialer,
+		Log:      conf.Log,
+	}, nil
+}
+
+// Send send file to server by the tcp protocol
+func (t tcpTransport) Send(file string, conf *Conf) error {
+	c, err := t.clientConf(file, conf)
+	if err != nil {
+		return err
+	}
+
+	return trackSend(t.network, file, func() error {
+		return client.Send(c)
+	})
+}
+
+// Fetch download file name from server by the tcp protocol
+func (t tcpTransport) Fetch(name string, dst storage.Storage, local string, conf *Conf) error {
+	c, err := t.clientConf(name, conf)
+	if err != nil {
+		return err
+	}
+
+	return trackFetch(t.network, dst, local, func() error {
+		return client.Fetch(c, dst, local)
+	})
+}
+
+// Serve serve clients until the listener is closed
+func (t tcpTransport) Serve(conf *ServerConf) error {
+	host, port, err := t.split(conf.Addr)
+	if err != nil {
+		return err
+	}
+
+	s, err := server.Listen(&server.Conf{
+		Addr:    host,
+		Port:    port,
+		MaxConn: conf.MaxConn,
+		Storage: conf.Storage,
+		Export:  conf.Export,
+		TLS:     conf.TLS,
+		Auth:    conf.Auth,
+		Policy:  conf.Policy,
+
+		MaxConnPerIP: conf.MaxConnPerIP,
+		ConnRate:     conf.ConnRate,
+
+		Limit:       conf.Limit,
+		ClientLimit: conf.ClientLimit,
+
+		Relay:     conf.Relay,
+		RelayHops: conf.RelayHops,
+		RelayTLS:  conf.RelayTLS,
+		RelayKey:  conf.RelayKey,
+
+		Network:  t.network,
+		Listener: conf.Listener,
+		Log:      conf.Log,
+	})
+	if err != nil {
+		return err
+	}
+
+	s.Start()
+
+	return nil
+}
+
+// split split addr to host and port, addr of a unix socket is its path
+func (t tcpTransport) split(addr string) (string, string, error) {
+	if t.network == "unix" {
+		return addr, "", nil
+	}
+
+	return net.SplitHostPort(addr)
+}
@@ -0,0 +1,99 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package transport_test
+
+import (
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/TechCatsLab/redalert/codec"
+	"github.com/TechCatsLab/redalert/memnet"
+	"github.com/TechCatsLab/redalert/storage"
+	"github.com/TechCatsLab/redalert/transport"
+)
+
+func TestTCP(t *testing.T) {
+	runCases(t, transport.TCP, []e2eCase{
+		{name: "send"},
+		{name: "send gzip", conf: transport.Conf{Codec: codec.Gzip}},
+		{name: "send sparse", conf: transport.Conf{Sparse: true}, sparse: true},
+		{name: "send delta", conf: transport.Conf{Delta: true}, delta: true},
+		{name: "get", get: true},
+	})
+}
+
+// corruptConn flip the last byte of its third write, the second file pack
+type corruptConn struct {
+	net.Conn
+	writes int
+}
+
+func (c *corruptConn) Write(p []byte) (int, error) {
+	if c.writes++; c.writes == 3 {
+		p = append([]byte(nil), p...)
+		p[len(p)-1] ^= 0xff
+	}
+
+	return c.Conn.Write(p)
+}
+
+// a file corrupted on the way fails the hash check of server, so the send
+// fails and the file isn't kept
+func TestTCPHashMismatch(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "a.bin")
+	writeSample(t, file, false)
+
+	network := memnet.New(memnet.Link{})
+	srv := storage.NewMemory()
+	serve(t, transport.TCP, network, &transport.ServerConf{
+		Addr:    server,
+		Storage: srv,
+	})
+
+	conf := transport.Conf{
+		Addr: server,
+		Dialer: func() (net.Conn, error) {
+			conn, err := network.Dial(server)
+			if err != nil {
+				return nil, err
+			}
+
+			return &corruptConn{Conn: conn}, nil
+		},
+	}
+
+	if err := transport.Get(transport.TCP).Send(file, &conf); err == nil {
+		t.Fatal("send of corrupted file succeeded")
+	}
+
+	if _, ok := srv.Bytes("a.bin"); ok {
+		t.Fatal("corrupted file is kept")
+	}
+}
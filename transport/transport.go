@@ -0,0 +1,174 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package transport
+
+import (
+	"crypto/tls"
+	"net"
+	"sort"
+	"sync"
+
+	"github.com/TechCatsLab/redalert/auth"
+	"github.com/TechCatsLab/redalert/logger"
+	"github.com/TechCatsLab/redalert/policy"
+	"github.com/TechCatsLab/redalert/storage"
+)
+
+// Names of transports shipped, Addr of unix ones is the path of socket
+const (
+	TCP      = "tcp"
+	UDP      = "udp"
+	Unix     = "unix"     // stream socket by the tcp protocol
+	Unixgram = "unixgram" // datagram socket by the udp protocol
+	WS       = "ws"       // WebSocket by the tcp protocol, Addr is host:port/path
+)
+
+// Conf is the configuration of a client, a transport ignores fields it
+// doesn't support. Fields of tcp are of the tcp protocol, used by unix and
+// ws too, fields of udp are used by unixgram too.
+type Conf struct {
+	Addr     string    // host:port of server, or path of socket
+	PackSize int       // Max size of a pack, chosen by transport if 0
+	Dest     string    // Path of file on server, base name of file if empty
+	Key      *auth.Key // Answer auth challenge of server if not nil
+	Limit    float64   // Bytes per second, no limit if 0
+	Codec    byte      // ID of codec to compress file, see codec, not compressed if 0
+	SkipSame bool      // Send MD5 of file, nothing is sent if server has the same file
+	Sparse   bool      // Send holes of file as their length
+
+	TLS    *tls.Config // Use TLS if not nil, tcp
+	Delta  bool        // Send delta against the old copy of file on server, tcp
+	Route  string      // Next hops after server as host:port,host:port, tcp
+	Secure bool        // Encrypt packets, udp
+	PSK    []byte      // Pre-shared key of encrypted session, udp
+	FEC    int         // Packs of a FEC group followed by a parity pack, udp
+
+	Dialer func() (net.Conn, error) // Dial server by it instead of Addr if not nil, such as a memnet conn
+	Log    logger.Logger            // Where messages go, default to logger.Default()
+}
+
+// ServerConf is the configuration of a server, a transport ignores fields
+// it doesn't support, see Conf for fields of tcp and udp
+type ServerConf struct {
+	Addr        string          // host:port to listen on, or path of socket
+	Storage     storage.Storage // Where received files go, default to protocol.DefaultDir
+	Export      storage.Source  // Where downloaded files come from, download is disabled if nil
+	Auth        auth.Keyring    // Clients must authenticate if not nil
+	Policy      *policy.Policy  // What clients may do, everything if nil
+	Limit       float64         // Bytes per second of all transfers, no limit if 0
+	ClientLimit float64         // Bytes per second of a client, by ID or source IP, no limit if 0
+
+	TLS          *tls.Config // Serve TLS if not nil, tcp
+	MaxConn      int         // Connection limit, tcp
+	MaxConnPerIP int         // Connection limit of a source IP, tcp
+	ConnRate     float64     // New connections per second of a source IP, tcp
+	Relay        bool        // Forward requests with a route to the next hop, tcp
+	RelayHops    []string    // Next hops as host:port a relay may connect, tcp
+	RelayTLS     *tls.Config // Connect next hop by TLS if not nil, tcp
+	RelayKey     *auth.Key   // Answer auth challenge of next hop if not nil, tcp
+	CacheCount   int         // Cache size, udp
+	Secure       bool        // Reject packets not encrypted, udp
+	PSK          []byte      // Pre-shared key of encrypted sessions, udp
+	Group        string      // Multicast group to receive files from, udp
+	Interface    string      // Network interface to join Group on, udp
+
+	Listener   net.Listener   // Accept conns on it instead of Addr if not nil, tcp and unix
+	PacketConn net.PacketConn // Serve on it instead of Addr if not nil, udp and unixgram
+	Log        logger.Logger  // Where messages go, default to logger.Default()
+}
+
+// Sender sends local files to a server
+type Sender interface {
+	Send(file string, conf *Conf) error
+}
+
+// Fetcher downloads file name from a server and saves it to dst as local
+type Fetcher interface {
+	Fetch(name string, dst storage.Storage, local string, conf *Conf) error
+}
+
+// Receiver serves clients and keeps files they send, it returns on error
+type Receiver interface {
+	Serve(conf *ServerConf) error
+}
+
+// Transport is a way to transfer files, such as tcp or udp
+type Transport interface {
+	Sender
+	Fetcher
+	Receiver
+}
+
+var (
+	mu         sync.RWMutex
+	transports = make(map[string]Transport)
+)
+
+func init() {
+	Register(TCP, tcpTransport{network: "tcp"})
+	Register(UDP, udpTransport{network: "udp"})
+	Register(Unix, tcpTransport{network: "unix"})
+	Register(Unixgram, udpTransport{network: "unixgram"})
+	Register(WS, wsTransport{})
+}
+
+// Register make transport t available as name
+func Register(name string, t Transport) {
+	if name == "" {
+		panic("transport: name is empty")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	transports[name] = t
+}
+
+// Get return transport of name, nil if not registered
+func Get(name string) Transport {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return transports[name]
+}
+
+// Names return names of registered transports in order
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	names := make([]string, 0, len(transports))
+	for name := range transports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
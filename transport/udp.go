@@ -0,0 +1,143 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package transport
+
+import (
+	"net"
+
+	"github.com/TechCatsLab/redalert/storage"
+	"github.com/TechCatsLab/redalert/udp/client"
+	"github.com/TechCatsLab/redalert/udp/server"
+)
+
+// udpTransport transfer files by udp/client and udp/server over network
+type udpTransport struct {
+	network string
+}
+
+// clientConf convert conf to udp client conf of file
+func (t udpTransport) clientConf(file string, conf *Conf) (*client.Conf, error) {
+	host, port, err := t.split(conf.Addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &client.Conf{
+		FileName:      file,
+		RemoteAddress: host,
+		RemotePort:    port,
+		PacketSize:    conf.PackSize,
+		Secure:        conf.Secure,
+		PSK:           conf.PSK,
+		Key:           conf.Key,
+		Dest:          conf.Dest,
+		Limit:         conf.Limit,
+		Codec:         conf.Codec,
+		SkipSame:      conf.SkipSame,
+		Sparse:        conf.Sparse,
+		FEC:           conf.FEC,
+		Network:       t.network,
+		Dialer:        conf.Dialer,
+		Log:           conf.Log,
+	}, nil
+}
+
+// Send send file to server by the udp protocol
+func (t udpTransport) Send(file string, conf *Conf) error {
+	c, err := t.clientConf(file, conf)
+	if err != nil {
+		return err
+	}
+
+	return trackSend(t.network, file, func() error {
+		cli, err := client.NewClient(c)
+		if err != nil {
+			return err
+		}
+
+		return cli.Start()
+	})
+}
+
+// Fetch download file name from server by the udp protocol
+func (t udpTransport) Fetch(name string, dst storage.Storage, local string, conf *Conf) error {
+	c, err := t.clientConf(name, conf)
+	if err != nil {
+		return err
+	}
+
+	return trackFetch(t.network, dst, local, func() error {
+		return client.Fetch(c, dst, local)
+	})
+}
+
+// Serve serve clients until the process exits
+func (t udpTransport) Serve(conf *ServerConf) error {
+	host, port, err := t.split(conf.Addr)
+	if err != nil {
+		return err
+	}
+
+	s, err := server.Listen(&server.Conf{
+		Address:    host,
+		Port:       port,
+		CacheCount: conf.CacheCount,
+		Storage:    conf.Storage,
+		Export:     conf.Export,
+		Secure:     conf.Secure,
+		PSK:        conf.PSK,
+		Auth:       conf.Auth,
+		Policy:     conf.Policy,
+		Group:      conf.Group,
+		Interface:  conf.Interface,
+		Network:    t.network,
+		Conn:       conf.PacketConn,
+		Log:        conf.Log,
+
+		Limit:       conf.Limit,
+		ClientLimit: conf.ClientLimit,
+	})
+	if err != nil {
+		return err
+	}
+
+	s.HandleClient()
+
+	return nil
+}
+
+// split split addr to host and port, addr of a unixgram socket is its path
+func (t udpTransport) split(addr string) (string, string, error) {
+	if t.network == "unixgram" {
+		return addr, "", nil
+	}
+
+	return net.SplitHostPort(addr)
+}
@@ -0,0 +1,115 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package transport_test
+
+import (
+	"errors"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/TechCatsLab/redalert/codec"
+	"github.com/TechCatsLab/redalert/memnet"
+	"github.com/TechCatsLab/redalert/storage"
+	"github.com/TechCatsLab/redalert/transport"
+	"github.com/TechCatsLab/redalert/udp/client"
+	"github.com/TechCatsLab/redalert/udp/remote"
+)
+
+func TestUDP(t *testing.T) {
+	runCases(t, transport.UDP, []e2eCase{
+		{name: "send"},
+		{name: "send gzip", conf: transport.Conf{Codec: codec.Gzip}},
+		{name: "send fec", conf: transport.Conf{FEC: 4}},
+		{name: "send sparse", conf: transport.Conf{Sparse: true}, sparse: true},
+		{name: "send encrypted", conf: transport.Conf{Secure: true}, secure: true},
+		{name: "get", get: true},
+	})
+}
+
+// expireConn expire its transfer on the server before its tenth write, as
+// the idle timer of server does after an outage
+type expireConn struct {
+	net.Conn
+	writes int
+}
+
+func (c *expireConn) Write(p []byte) (int, error) {
+	if c.writes++; c.writes == 10 {
+		remote.Service.Close(c.LocalAddr(), errors.New("expired"))
+	}
+
+	return c.Conn.Write(p)
+}
+
+// a client whose transfer expired on the server is told so and stops, it
+// doesn't resend forever
+func TestUDPExpired(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "a.bin")
+	writeSample(t, file, false)
+
+	network := memnet.New(memnet.Link{})
+	srv := storage.NewMemory()
+	serve(t, transport.UDP, network, &transport.ServerConf{
+		Addr:    server,
+		Storage: srv,
+	})
+
+	conf := transport.Conf{
+		Addr:     server,
+		PackSize: 1024,
+		Dialer: func() (net.Conn, error) {
+			conn, err := network.DialPacket(server)
+			if err != nil {
+				return nil, err
+			}
+
+			return &expireConn{Conn: conn}, nil
+		},
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- transport.Get(transport.UDP).Send(file, &conf)
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, client.ErrFromServer) {
+			t.Fatalf("got %v, want %v", err, client.ErrFromServer)
+		}
+	case <-time.After(caseTimeout):
+		t.Fatal("client of expired transfer hangs")
+	}
+
+	if _, ok := srv.Bytes("a.bin"); ok {
+		t.Fatal("expired file is kept")
+	}
+}
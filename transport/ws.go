@@ -0,0 +1,128 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package transport
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/TechCatsLab/redalert/storage"
+	"github.com/TechCatsLab/redalert/ws"
+)
+
+// wsTransport transfer files by the tcp protocol over WebSocket, so it gets
+// through HTTP proxies and firewalls. Addr is host:port/path, TLS makes it
+// wss, Dialer and Listener give conns under WebSocket.
+type wsTransport struct{}
+
+// stream is the transport of conns over WebSocket
+var stream = tcpTransport{network: "tcp"}
+
+// clientConf convert conf to dial server by WebSocket
+func (wsTransport) clientConf(conf *Conf) *Conf {
+	c := *conf
+	c.Addr, _ = ws.SplitAddr(conf.Addr)
+	c.TLS = nil
+	c.Dialer = func() (net.Conn, error) {
+		if conf.Dialer == nil {
+			return ws.Dial(conf.Addr, conf.TLS)
+		}
+
+		conn, err := conf.Dialer()
+		if err != nil {
+			return nil, err
+		}
+
+		wsConn, err := ws.Client(conn, conf.Addr)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+
+		return wsConn, nil
+	}
+
+	return &c
+}
+
+// Send send file to server by the tcp protocol over WebSocket
+func (t wsTransport) Send(file string, conf *Conf) error {
+	return stream.Send(file, t.clientConf(conf))
+}
+
+// Fetch download file name from server by the tcp protocol over WebSocket
+func (t wsTransport) Fetch(name string, dst storage.Storage, local string, conf *Conf) error {
+	return stream.Fetch(name, dst, local, t.clientConf(conf))
+}
+
+// Serve serve clients upgraded at path of an http server, until the
+// listener is closed
+func (wsTransport) Serve(conf *ServerConf) error {
+	hostport, path := ws.SplitAddr(conf.Addr)
+
+	raw := conf.Listener
+	if raw == nil {
+		l, err := net.Listen("tcp", hostport)
+		if err != nil {
+			return err
+		}
+		raw = l
+	}
+	if conf.TLS != nil {
+		raw = tls.NewListener(raw, conf.TLS)
+	}
+
+	ln := ws.NewListener(raw.Addr())
+	mux := http.NewServeMux()
+	mux.Handle(path, ln)
+	srv := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- srv.Serve(raw)
+		ln.Close()
+	}()
+
+	c := *conf
+	c.Addr = hostport
+	c.TLS = nil
+	c.Listener = ln
+	if err := stream.Serve(&c); err != nil {
+		raw.Close()
+		return err
+	}
+
+	return <-done
+}
@@ -34,12 +34,17 @@ import (
 	"crypto/md5"
 	"errors"
 	"io"
-	"log"
 	"net"
 	"os"
 	"time"
 
+	"github.com/TechCatsLab/redalert/codec"
+	"github.com/TechCatsLab/redalert/fec"
+	"github.com/TechCatsLab/redalert/logger"
+	"github.com/TechCatsLab/redalert/metrics"
 	"github.com/TechCatsLab/redalert/protocol"
+	"github.com/TechCatsLab/redalert/ratelimit"
+	"github.com/TechCatsLab/redalert/sparse"
 )
 
 const (
@@ -50,6 +55,11 @@ const (
 var (
 	// ErrLittleHead little header
 	ErrLittleHead = errors.New("Bytes of header too little to write")
+
+	// ErrFEC FEC group size out of range
+	ErrFEC = errors.New("FEC group size out of range")
+
+	errPresent = errors.New("File is present on server")
 )
 
 // Client - UDP Client
@@ -59,32 +69,52 @@ type Client struct {
 	handler Handler
 
 	sendChan chan struct{}
+	errChan  chan error
+	network  string // network of server in metrics
+	log      logger.Logger
 }
 
 // NewClient Create a UDP client
 func NewClient(conf *Conf) (*Client, error) {
-	addr, err := net.ResolveUDPAddr("udp", conf.RemoteAddress+":"+conf.RemotePort)
+	file, err := os.Open(conf.FileName)
 	if err != nil {
-		log.Fatalln("[ERROR]:Can't resolve address:", err)
+		return nil, err
 	}
 
-	conn, err := net.DialUDP("udp", nil, addr)
-
+	client, err := newClient(conf, file)
 	if err != nil {
-		log.Fatalln("[ERROR]:Can't dial:", err)
+		file.Close()
+		return nil, err
 	}
 
-	conn.SetReadBuffer(bufferSize)
-	conn.SetWriteBuffer(bufferSize)
+	return client, nil
+}
 
-	file, err := os.Open(conf.FileName)
+// newClient dial server to send file
+func newClient(conf *Conf, file *os.File) (*Client, error) {
+	fileInfo, err := file.Stat()
 	if err != nil {
-		log.Fatalln("[ERROR]:Open file:", err)
+		return nil, err
 	}
 
-	fileInfo, err := os.Stat(conf.FileName)
+	conn, err := dial(conf)
 	if err != nil {
-		log.Fatalln("[ERROR]:Get fileinfo:", err)
+		return nil, err
+	}
+
+	log := logger.Or(conf.Log).With("server", conn.RemoteAddr().String(), "file", conf.FileName)
+
+	var sconn net.Conn = conn
+	if conf.Secure {
+		if sconn, err = handshake(conn, conf.PSK); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	sconn = ratelimit.NewConn(sconn, ratelimit.NewByteBucket(conf.Limit))
+
+	if conf.PacketSize == 0 {
+		conf.PacketSize = packetSize(sconn, conn, log)
 	}
 
 	if conf.PacketSize < protocol.FirstPacketSize {
@@ -97,12 +127,25 @@ func NewClient(conf *Conf) (*Client, error) {
 
 	client := Client{
 		conf:     conf,
-		sendChan: make(chan struct{}),
+		sendChan: make(chan struct{}, 1),
+		errChan:  make(chan error, 1),
+		network:  conn.RemoteAddr().Network(),
+		log:      log,
+	}
+
+	name := conf.Dest
+	if name == "" {
+		name = fileInfo.Name()
+	}
+
+	if len(name) > protocol.FirstPacketSize-protocol.FixedHeaderSize {
+		conn.Close()
+		return nil, ErrNameTooLong
 	}
 
 	client.proto = &protocol.Proto{
 		HeaderType: protocol.HeaderRequestType,
-		HeaderSize: uint16(protocol.FixedHeaderSize + len(fileInfo.Name())),
+		HeaderSize: uint16(protocol.FixedHeaderSize + len(name)),
 		PackSize:   uint16(client.conf.PacketSize),
 		PackOrder:  0,
 	}
@@ -113,15 +156,57 @@ func NewClient(conf *Conf) (*Client, error) {
 
 	decode.Buffer = bytes.NewBuffer(decode.Body)
 
+	var c codec.Codec
+	if conf.Codec != 0 {
+		if c = codec.Get(conf.Codec); c == nil {
+			conn.Close()
+			return nil, ErrCodec
+		}
+	}
+
 	handler := &DefaultHandler{
-		conn:      conn,
+		conn:      sconn,
 		proto:     client.proto,
 		hash:      md5.New(),
 		replyPack: make([]byte, protocol.ReplySize),
 		pack:      &decode,
 		sendChan:  client.sendChan,
+		errChan:   client.errChan,
 		file:      file,
 		fileInfo:  fileInfo,
+		name:      name,
+		key:       conf.Key,
+		codecID:   conf.Codec,
+		codec:     c,
+		skipSame:  conf.SkipSame,
+		room:      conf.PacketSize - protocol.FixedHeaderSize,
+		log:       log,
+	}
+
+	if conf.FEC != 0 {
+		if conf.FEC < fec.MinGroup || conf.FEC > fec.MaxGroup {
+			conn.Close()
+			return nil, ErrFEC
+		}
+
+		handler.room -= fec.Overhead
+		handler.group = &fec.Encoder{}
+		handler.bufs = make([][]byte, conf.FEC+1)
+		for i := range handler.bufs {
+			handler.bufs[i] = make([]byte, conf.PacketSize)
+		}
+		handler.packs = make([][]byte, 0, conf.FEC+1)
+	}
+
+	if c != nil {
+		handler.raw = make([]byte, handler.room-1)
+	}
+
+	if conf.Sparse {
+		if handler.sparse, err = sparse.NewReader(file); err != nil {
+			conn.Close()
+			return nil, err
+		}
 	}
 
 	client.handler = handler
@@ -129,47 +214,65 @@ func NewClient(conf *Conf) (*Client, error) {
 	return &client, nil
 }
 
-// Start - Client start run
-func (c *Client) Start() (err error) {
-	defer func() {
-		if err != nil {
-			log.Fatal("[ERROR]:Start", err)
-		}
-	}()
-
-	c.handler.OnReceive()
+// Start - Client start run, it returns once the file is sent
+func (c *Client) Start() error {
+	defer c.handler.close()
 
 	begin := time.Now()
 
-	err = c.handler.OnProto()
+	err := c.handler.OnProto()
+	if err == errPresent {
+		c.log.Info("file is present on server")
+		return nil
+	}
 	if err != nil {
-		return
+		return err
 	}
 
+	c.handler.OnReceive()
+
+	// resends since the last reply, the server is gone if it never replies
+	resend := 0
+
 	for {
 		select {
 		case <-c.sendChan:
+			resend = 0
 			err := c.handler.OnSend()
 
+			if err == io.EOF {
+				continue
+			}
+
 			if err != nil {
-				if err == io.EOF {
-					err = nil
-					log.Println("[TIME]:", time.Now().Sub(begin))
+				c.log.Error("send error", "err", err)
 
-					return err
-				}
+				return err
+			}
 
-				log.Fatal("[ERROR]: Send error", err)
+		case err := <-c.errChan:
+			if err == io.EOF {
+				c.log.Info("send file finish", "elapsed", time.Since(begin))
+
+				return nil
 			}
+
+			return err
+
 		case <-time.After(resendInterval * time.Millisecond):
+			if resend++; resend > maxResend {
+				return ErrTimeout
+			}
+
 			num, err := c.handler.write()
 			if err != nil {
-				log.Fatal("[ERROR]: Resend", num, "word.", err)
+				c.log.Error("resend error", "bytes", num, "err", err)
 
 				return err
 			}
 
-			log.Println("[RESEND]: ", num, "word.")
+			metrics.Retransmits.With(c.network).Inc()
+			c.log.Debug("resend pack", "bytes", num)
 		}
 	}
 }
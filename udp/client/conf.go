@@ -29,10 +29,31 @@
 
 package client
 
+import (
+	"net"
+
+	"github.com/TechCatsLab/redalert/auth"
+	"github.com/TechCatsLab/redalert/logger"
+)
+
 // Conf - Client 的配置
 type Conf struct {
-	FileName      string // File name
-	RemoteAddress string // Remote address
-	RemotePort    string // Remote port
-	PacketSize    int    // Packet max size
+	FileName      string    // File name
+	RemoteAddress string    // Remote address
+	RemotePort    string    // Remote port
+	PacketSize    int       // Packet max size, probed if 0
+	Secure        bool      // Encrypt packets
+	PSK           []byte    // Pre-shared key of encrypted session, optional
+	Key           *auth.Key // Answer auth challenge of server if not nil
+	Dest          string    // Path of file on server, base name of FileName if empty
+	Limit         float64   // Bytes per second, no limit if 0
+	Codec         byte      // ID of codec to compress packets, see codec, not compressed if 0
+	SkipSame      bool      // Send MD5 of file, nothing is sent if server has the same file
+	Sparse        bool      // Send holes of file as their length
+	FEC           int       // Packs of a FEC group followed by a parity pack, no FEC if 0
+	Receivers     int       // Reports of multicast to wait for, until receivers are quiet if 0
+	Network       string    // udp or unixgram with RemoteAddress as path of socket, default to udp
+
+	Dialer func() (net.Conn, error) // Dial server by it instead of Network if not nil
+	Log    logger.Logger            // Where messages go, default to logger.Default()
 }
@@ -0,0 +1,110 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+)
+
+// ErrNetwork network of conf is neither udp nor unixgram
+var ErrNetwork = errors.New("Network not supported")
+
+// sockets counts unixgram sockets bound by the process
+var sockets uint32
+
+// boundConn is a unixgram conn bound to a temp path, the path is removed
+// once it's closed
+type boundConn struct {
+	*net.UnixConn
+	path string
+}
+
+func (c *boundConn) Close() error {
+	err := c.UnixConn.Close()
+	os.Remove(c.path)
+
+	return err
+}
+
+// dial connect server of conf by conf.Network. A unixgram conn is bound to a
+// temp path, so server can reply to it.
+func dial(conf *Conf) (net.Conn, error) {
+	if conf.Dialer != nil {
+		return conf.Dialer()
+	}
+
+	switch conf.Network {
+	case "", "udp":
+	case "unixgram":
+		return dialUnixgram(conf.RemoteAddress)
+	default:
+		return nil, ErrNetwork
+	}
+
+	addr, err := net.ResolveUDPAddr("udp", net.JoinHostPort(conf.RemoteAddress, conf.RemotePort))
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.DialUDP("udp", nil, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	conn.SetReadBuffer(bufferSize)
+	conn.SetWriteBuffer(bufferSize)
+
+	return conn, nil
+}
+
+// dialUnixgram connect unixgram socket at path
+func dialUnixgram(path string) (net.Conn, error) {
+	n := atomic.AddUint32(&sockets, 1)
+	local := filepath.Join(os.TempDir(), fmt.Sprintf("gcp-%d-%d.sock", os.Getpid(), n))
+	os.Remove(local)
+
+	conn, err := net.DialUnix("unixgram",
+		&net.UnixAddr{Name: local, Net: "unixgram"},
+		&net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		os.Remove(local)
+		return nil, err
+	}
+
+	conn.SetReadBuffer(bufferSize)
+	conn.SetWriteBuffer(bufferSize)
+
+	return &boundConn{UnixConn: conn, path: local}, nil
+}
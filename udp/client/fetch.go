@@ -0,0 +1,281 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package client
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/TechCatsLab/redalert/auth"
+	"github.com/TechCatsLab/redalert/codec"
+	"github.com/TechCatsLab/redalert/logger"
+	"github.com/TechCatsLab/redalert/metrics"
+	"github.com/TechCatsLab/redalert/protocol"
+	"github.com/TechCatsLab/redalert/ratelimit"
+	"github.com/TechCatsLab/redalert/storage"
+)
+
+const (
+	maxResend = 20
+)
+
+var (
+	// ErrNotFound file not found on server
+	ErrNotFound = errors.New("File not found on server")
+	// ErrFromServer server reply with error
+	ErrFromServer = errors.New("Got error from server")
+	// ErrHashNotMatch hash of received file not match with server's
+	ErrHashNotMatch = errors.New("Hash value not match")
+	// ErrTimeout server not response
+	ErrTimeout = errors.New("Server time out")
+	// ErrNameTooLong file name can't put into the first packet
+	ErrNameTooLong = errors.New("File name too long")
+	// ErrForbidden server denies the request by policy
+	ErrForbidden = errors.New("Request denied by server")
+	// ErrCodec codec not supported
+	ErrCodec = errors.New("Codec not supported")
+)
+
+// Fetch download file conf.FileName from server and save it to dst as name.
+// The server sends packets the way a client pushes a file, and Fetch acks
+// every packet with a HeaderAckType packet.
+func Fetch(conf *Conf, dst storage.Storage, name string) error {
+	rawConn, err := dial(conf)
+	if err != nil {
+		return err
+	}
+	defer rawConn.Close()
+
+	conn := rawConn
+	if conf.Secure {
+		if conn, err = handshake(rawConn, conf.PSK); err != nil {
+			return err
+		}
+	}
+	conn = ratelimit.NewConn(conn, ratelimit.NewByteBucket(conf.Limit))
+
+	if len(conf.FileName) > protocol.FirstPacketSize-protocol.FixedHeaderSize {
+		return ErrNameTooLong
+	}
+
+	if conf.PacketSize == 0 {
+		conf.PacketSize = packetSize(conn, rawConn, logger.Or(conf.Log))
+	}
+
+	if conf.PacketSize < protocol.FirstPacketSize {
+		conf.PacketSize = protocol.FirstPacketSize
+	}
+
+	if conf.PacketSize > protocol.MaxPacketSize {
+		conf.PacketSize = protocol.MaxPacketSize
+	}
+
+	request := protocol.Encode{
+		Body: make([]byte, protocol.FirstPacketSize),
+	}
+	request.Buffer = bytes.NewBuffer(request.Body)
+	request.Marshal(&protocol.Proto{
+		HeaderType: protocol.HeaderGetType,
+		HeaderSize: uint16(protocol.FixedHeaderSize + len(conf.FileName)),
+		PackSize:   uint16(conf.PacketSize),
+	})
+	copy(request.Body[protocol.FileNameOffset:], conf.FileName)
+
+	var c codec.Codec
+	if conf.Codec != 0 {
+		if c = codec.Get(conf.Codec); c == nil {
+			return ErrCodec
+		}
+
+		opts := protocol.Options{}
+		opts.SetByte(protocol.OptionCodec, conf.Codec)
+		if err = opts.Marshal(request.Body[protocol.FixedHeaderSize+len(conf.FileName):]); err != nil {
+			return err
+		}
+	}
+
+	ack := protocol.Encode{
+		Body: make([]byte, protocol.FixedHeaderSize),
+	}
+	ack.Buffer = bytes.NewBuffer(ack.Body)
+	ackProto := protocol.Proto{
+		HeaderType: protocol.HeaderAckType,
+		HeaderSize: protocol.FixedHeaderSize,
+	}
+
+	// last is the packet to resend when server not response
+	last := request.Body
+	sendAck := func(order uint32) error {
+		ackProto.PackOrder = order
+		ack.Marshal(&ackProto)
+		last = ack.Body
+
+		_, err := conn.Write(ack.Body)
+		return err
+	}
+
+	if _, err = conn.Write(request.Body); err != nil {
+		return err
+	}
+
+	var (
+		file   storage.Object
+		offset int64
+		order  = uint32(1)
+		hash   = md5.New()
+		proto  = protocol.Proto{}
+		pack   = protocol.Encode{Body: make([]byte, conf.PacketSize)}
+		resend = 0
+	)
+
+	abort := func(err error) error {
+		if file != nil {
+			file.Abort()
+		}
+
+		return err
+	}
+
+	for {
+		conn.SetReadDeadline(time.Now().Add(resendInterval * time.Millisecond))
+
+		num, err := conn.Read(pack.Body)
+		if err != nil {
+			if e, ok := err.(net.Error); ok && e.Timeout() {
+				resend++
+				if resend > maxResend {
+					return abort(ErrTimeout)
+				}
+
+				if _, err = conn.Write(last); err != nil {
+					return abort(err)
+				}
+				metrics.Retransmits.With(conn.RemoteAddr().Network()).Inc()
+				continue
+			}
+
+			return abort(err)
+		}
+		resend = 0
+
+		if num == protocol.ReplySize+auth.ChallengeSize && binary.BigEndian.Uint32(pack.Body) == protocol.ReplyChallenge {
+			if conf.Key == nil {
+				return abort(auth.ErrRequired)
+			}
+
+			last = auth.Marshal(conf.Key, pack.Body[protocol.ReplySize:num], request.Body)
+			if _, err = conn.Write(last); err != nil {
+				return abort(err)
+			}
+			continue
+		}
+
+		if num == protocol.ReplySize {
+			switch binary.BigEndian.Uint32(pack.Body) {
+			case protocol.ReplyNotFound:
+				return abort(ErrNotFound)
+			case protocol.ReplyUnauthorized:
+				return abort(auth.ErrUnauthorized)
+			case protocol.ReplyForbidden:
+				return abort(ErrForbidden)
+			case protocol.ReplyUnsupported:
+				return abort(ErrCodec)
+			default:
+				return abort(ErrFromServer)
+			}
+		}
+
+		if num < protocol.FixedHeaderSize {
+			continue
+		}
+
+		pack.Buffer = bytes.NewBuffer(pack.Body)
+		pack.Unmarshal(&proto)
+
+		if protocol.FixedHeaderSize+int(proto.PackSize) > num {
+			continue
+		}
+		body := pack.Body[protocol.FixedHeaderSize : protocol.FixedHeaderSize+int(proto.PackSize)]
+
+		// repeated packet, the ack is lost
+		if proto.PackOrder < order {
+			if err = sendAck(proto.PackOrder); err != nil {
+				return abort(err)
+			}
+			continue
+		}
+
+		if proto.PackOrder > order {
+			continue
+		}
+
+		if file == nil {
+			if file, err = dst.Create(name); err != nil {
+				return err
+			}
+		}
+
+		switch proto.HeaderType {
+		case protocol.HeaderFileType:
+			if c != nil {
+				if body, err = codec.Unpack(c, body, protocol.MaxPacketSize); err != nil {
+					return abort(err)
+				}
+			}
+
+			if _, err = file.WriteAt(body, offset); err != nil {
+				return abort(err)
+			}
+			offset += int64(len(body))
+			hash.Write(body)
+
+			if err = sendAck(order); err != nil {
+				return abort(err)
+			}
+			order++
+
+		case protocol.HeaderFileFinishType:
+			if !bytes.Equal(body, hash.Sum(nil)) {
+				sendAck(protocol.ReplyError)
+				return abort(ErrHashNotMatch)
+			}
+
+			if err = sendAck(protocol.ReplyFinish); err != nil {
+				return abort(err)
+			}
+
+			return file.Commit()
+		}
+	}
+}
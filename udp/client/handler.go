@@ -31,16 +31,25 @@ package client
 
 import (
 	"bytes"
+	"crypto/md5"
 	"encoding/binary"
-	"fmt"
+	"encoding/hex"
+	"errors"
 	"hash"
 	"io"
-	"log"
 	"net"
 	"os"
 	"strings"
-
+	"sync/atomic"
+	"time"
+
+	"github.com/TechCatsLab/redalert/auth"
+	"github.com/TechCatsLab/redalert/codec"
+	"github.com/TechCatsLab/redalert/fec"
+	"github.com/TechCatsLab/redalert/logger"
+	"github.com/TechCatsLab/redalert/metrics"
 	"github.com/TechCatsLab/redalert/protocol"
+	"github.com/TechCatsLab/redalert/sparse"
 )
 
 // Handler interface
@@ -49,11 +58,12 @@ type Handler interface {
 	OnProto() error
 	OnSend() error
 	write() (int, error)
+	close()
 }
 
 // DefaultHandler default handler
 type DefaultHandler struct {
-	conn  *net.UDPConn
+	conn  net.Conn
 	proto *protocol.Proto
 
 	replyPack []byte
@@ -63,11 +73,32 @@ type DefaultHandler struct {
 
 	file     *os.File
 	fileInfo os.FileInfo
+	name     string // path of file on server
 
 	sendChan chan struct{}
+	errChan  chan<- error // error of server or reading replies
+	waiting  uint32       // order of pack waiting for reply, 0 if none, set atomically
+
+	key *auth.Key
+
+	codecID byte
+	codec   codec.Codec // compress packs if not nil
+	raw     []byte      // content of file to compress
+
+	skipSame bool           // send MD5 of file in request
+	sparse   *sparse.Reader // read file by data and holes if not nil
+	room     int            // max size of content of a pack
+
+	group   *fec.Encoder // send packs by FEC groups if not nil
+	bufs    [][]byte     // buffers of packs of group and its parity
+	packs   [][]byte     // packs of the group being sent
+	reverse bool         // send packs of group from the last one
+
+	log logger.Logger
 }
 
-// OnProto discuss proto
+// OnProto discuss proto, it returns once server accepts the request and
+// the first file pack is ready to send
 func (h *DefaultHandler) OnProto() error {
 	firstPacket := protocol.Encode{
 		Body: make([]byte, protocol.FirstPacketSize),
@@ -77,99 +108,327 @@ func (h *DefaultHandler) OnProto() error {
 	firstPacket.Marshal(h.proto)
 	h.proto.HeaderType = protocol.HeaderFileType
 	h.pack.Marshal(h.proto)
-	nameReader := strings.NewReader(h.fileInfo.Name())
+	nameReader := strings.NewReader(h.name)
 	nameReader.Read(firstPacket.Body[protocol.FixedHeaderSize:])
 
-	log.Println("writeFirst - headBytes:", firstPacket.Body[:h.proto.HeaderSize])
+	opts := protocol.Options{}
+	opts.SetUint64(protocol.OptionSize, uint64(h.fileInfo.Size()))
+	if h.codec != nil {
+		opts.SetByte(protocol.OptionCodec, h.codecID)
+	}
+	if h.group != nil {
+		opts.SetByte(protocol.OptionFEC, byte(len(h.bufs)-1))
+	}
+	if h.skipSame {
+		sum, err := fileSum(h.file)
+		if err != nil {
+			return err
+		}
+		opts[protocol.OptionHash] = sum
+	}
+	if err := opts.Marshal(firstPacket.Body[h.proto.HeaderSize:]); err != nil {
+		return err
+	}
 
-	num, err := h.conn.Write(firstPacket.Body)
+	h.log.Info("send file", "size", h.fileInfo.Size(), "packet", len(h.pack.Body))
 
-	if err != nil {
-		log.Fatal("writeFirst err:", err)
+	packet := firstPacket.Body
+	reply := make([]byte, protocol.ReplySize+auth.ChallengeSize)
+	defer h.conn.SetReadDeadline(time.Time{})
+
+	for resend := 0; ; {
+		num, err := h.conn.Write(packet)
+		if err != nil {
+			return err
+		}
+
+		h.log.Debug("send request", "bytes", num)
+
+		h.conn.SetReadDeadline(time.Now().Add(resendInterval * time.Millisecond))
+		num, err = h.conn.Read(reply)
+		if err != nil {
+			if e, ok := err.(net.Error); ok && e.Timeout() {
+				resend++
+				if resend > maxResend {
+					return ErrTimeout
+				}
+				h.log.Debug("resend request")
+				metrics.Retransmits.With(h.conn.RemoteAddr().Network()).Inc()
+				continue
+			}
+
+			return err
+		}
+
+		if num < protocol.ReplySize {
+			continue
+		}
+
+		switch binary.BigEndian.Uint32(reply) {
+		case 0:
+			h.proto.PackOrder = 1
+			h.sendChan <- struct{}{}
+
+			return nil
+
+		case protocol.ReplyChallenge:
+			if h.key == nil {
+				return auth.ErrRequired
+			}
+
+			packet = auth.Marshal(h.key, reply[protocol.ReplySize:num], firstPacket.Body)
+			resend = 0
+
+		case protocol.ReplyUnauthorized:
+			return auth.ErrUnauthorized
+
+		case protocol.ReplyForbidden:
+			return ErrForbidden
+
+		case protocol.ReplyUnsupported:
+			return ErrCodec
+
+		case protocol.ReplyPresent:
+			return errPresent
+
+		default:
+			return ErrFromServer
+		}
 	}
+}
 
-	log.Println("writeFirst - 写了", num, "个字符。")
+// fileSum return MD5 of file, and seek back to the beginning of file
+func fileSum(file *os.File) ([]byte, error) {
+	hash := md5.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return nil, err
+	}
 
-	return err
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	return hash.Sum(nil), nil
 }
 
 // OnReceive receive back bytes
 func (h *DefaultHandler) OnReceive() {
 	go func() {
 		for {
-			num, err := h.conn.Read(h.replyPack)
+			_, err := h.conn.Read(h.replyPack)
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+
 			if err != nil {
-				log.Fatal("[ERROR]:readFromUDP - read", num, "byte，", "error：", err)
+				h.log.Error("read reply error", "err", err)
+				h.errChan <- err
 				return
 			}
 
 			packOrder := binary.BigEndian.Uint32(h.replyPack)
 
 			if protocol.ReplyFinish == packOrder {
-				log.Println("Pass file finish.")
+				h.errChan <- io.EOF
 				return
 			}
 
-			log.Println("[RECEIVE]:Receive length:", num)
+			if protocol.ReplyError == packOrder {
+				h.errChan <- ErrFromServer
+				return
+			}
 
-			h.proto.PackOrder = packOrder + 1
+			if protocol.ReplyForbidden == packOrder {
+				h.errChan <- ErrForbidden
+				return
+			}
 
-			log.Println("[RECEIVE]: Pack order:", h.proto.PackOrder)
+			// repeated reply of a resent pack
+			if packOrder == 0 || !atomic.CompareAndSwapUint32(&h.waiting, packOrder, 0) {
+				continue
+			}
 
-			h.sendChan <- struct{}{}
+			h.log.Debug("receive reply", "order", packOrder)
 
-			log.Println("[RECEIVE]: Begin send file")
+			h.sendChan <- struct{}{}
 		}
 	}()
 }
 
-// OnSend send file
+// OnSend send file, a pack or a FEC group of packs at a time
 func (h *DefaultHandler) OnSend() error {
-	binary.BigEndian.PutUint32(h.pack.Body[protocol.PackOrderOffset:], h.proto.PackOrder)
-	num, err := h.file.Read(h.pack.Body[protocol.FixedHeaderSize:])
+	if h.group != nil {
+		return h.sendGroup()
+	}
 
+	err := h.next(h.proto.PackOrder)
+	if err == io.EOF {
+		return h.finish()
+	}
 	if err != nil {
-		if err == io.EOF {
-			log.Println("WriteFile - 传送文件结束！")
+		h.log.Error("read file error", "err", err)
+
+		return err
+	}
 
-			hhash := h.hash.Sum(nil)
+	order := h.proto.PackOrder
+	h.proto.PackOrder++
+	atomic.StoreUint32(&h.waiting, order)
 
-			log.Println("文件MD5值：", hhash)
+	num, err := h.write()
+	if err != nil {
+		h.log.Error("send pack error", "bytes", num, "err", err)
 
-			md5Reader := bytes.NewReader(hhash)
-			md5Reader.Read(h.pack.Body[protocol.FixedHeaderSize:])
-			h.pack.Body[0] = protocol.HeaderFileFinishType
+		return err
+	}
 
-			h.write()
-			h.conn.Close()
-			h.file.Close()
+	h.log.Debug("send pack", "order", order, "bytes", num)
 
-			return io.EOF
-		}
-		log.Fatal("[ERROR]:Read file:", err)
+	return nil
+}
+
+// next read next pack of file into h.pack as pack of order, io.EOF at the
+// end of file
+func (h *DefaultHandler) next(order uint32) error {
+	binary.BigEndian.PutUint32(h.pack.Body[protocol.PackOrderOffset:], order)
+	buf := h.pack.Body[protocol.FixedHeaderSize : protocol.FixedHeaderSize+h.room]
+	if h.codec != nil {
+		buf = h.raw
+	}
 
+	var (
+		num  int
+		hole int64
+		err  error
+	)
+	if h.sparse != nil {
+		num, hole, err = h.sparse.Next(buf)
+	} else {
+		num, err = h.file.Read(buf)
+	}
+
+	if err != nil {
 		return err
 	}
 
-	log.Println("[SEND]:Read", num, "word.")
+	if hole > 0 {
+		return h.nextHole(hole)
+	}
+
+	h.pack.Body[0] = protocol.HeaderFileType
+	h.hash.Write(buf[:num])
 
-	h.hash.Write(h.pack.Body[protocol.FixedHeaderSize : protocol.FixedHeaderSize+num])
+	if h.codec != nil {
+		packed, err := codec.Pack(h.codec, buf[:num])
+		if err != nil {
+			return err
+		}
+		num = copy(h.pack.Body[protocol.FixedHeaderSize:], packed)
+	}
 
 	binary.BigEndian.PutUint16(h.pack.Body[protocol.PackSizeOffset:], uint16(num))
 
-	num, err = h.write()
-	if err != nil {
-		log.Fatal("WriteFile - 写了", num, "个字符。", "文件传输错误：", err)
+	return nil
+}
+
+// finish send hash of file to server, it's resent until server replies
+// ReplyFinish
+func (h *DefaultHandler) finish() error {
+	hhash := h.hash.Sum(nil)
+
+	h.log.Debug("send finish", "hash", hex.EncodeToString(hhash))
+
+	md5Reader := bytes.NewReader(hhash)
+	md5Reader.Read(h.pack.Body[protocol.FixedHeaderSize:])
+	h.pack.Body[0] = protocol.HeaderFileFinishType
+	h.packs = h.packs[:0]
+
+	h.write()
+
+	return io.EOF
+}
 
+// close close conn and file
+func (h *DefaultHandler) close() {
+	h.conn.Close()
+	h.file.Close()
+}
+
+// sendGroup send a FEC group of packs and its parity pack, the last group
+// may be shorter, the reply of its last pack is waited for
+func (h *DefaultHandler) sendGroup() error {
+	first := h.proto.PackOrder
+	h.group.Reset()
+	h.packs = h.packs[:0]
+	h.reverse = false
+
+	for i := 0; i < len(h.bufs)-1; i++ {
+		err := h.next(first + uint32(i))
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		size := protocol.FixedHeaderSize + int(binary.BigEndian.Uint16(h.pack.Body[protocol.PackSizeOffset:]))
+		pack := append(h.bufs[i][:0], h.pack.Body[:size]...)
+		h.packs = append(h.packs, pack)
+		h.group.Add(pack)
+	}
+
+	if len(h.packs) == 0 {
+		return h.finish()
+	}
+
+	parity := h.bufs[len(h.packs)]
+	h.packs = append(h.packs, parity[:h.group.Marshal(parity, first)])
+	h.proto.PackOrder = first + uint32(h.group.Count())
+	atomic.StoreUint32(&h.waiting, h.proto.PackOrder-1)
+
+	h.log.Debug("send group", "packs", h.group.Count(), "order", first)
+
+	_, err := h.write()
+
+	return err
+}
+
+// nextHole make h.pack a hole of file
+func (h *DefaultHandler) nextHole(hole int64) error {
+	h.log.Debug("send hole", "bytes", hole)
+
+	if err := sparse.WriteZeros(h.hash, hole); err != nil {
 		return err
 	}
 
-	log.Println("WriteFile - 写了", num, "个字符。")
+	h.pack.Body[0] = protocol.HeaderHoleType
+	binary.BigEndian.PutUint16(h.pack.Body[protocol.PackSizeOffset:], sparse.HoleSize)
+	binary.BigEndian.PutUint64(h.pack.Body[protocol.FixedHeaderSize:], uint64(hole))
 
 	return nil
 }
 
+// write send h.pack, or all packs of the FEC group being sent. A group is
+// resent from the other end each time, so packs dropped at the tail of a
+// burst by a full buffer go first.
 func (h *DefaultHandler) write() (int, error) {
-	fmt.Println("[DEBUG]pack body:", h.pack.Body)
+	if len(h.packs) > 0 {
+		total := 0
+		for i := range h.packs {
+			if h.reverse {
+				i = len(h.packs) - 1 - i
+			}
+
+			n, err := h.conn.Write(h.packs[i])
+			total += n
+			if err != nil {
+				return total, err
+			}
+		}
+		h.reverse = !h.reverse
+
+		return total, nil
+	}
+
 	return h.conn.Write(h.pack.Body)
 }
@@ -0,0 +1,399 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package client
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/TechCatsLab/redalert/auth"
+	"github.com/TechCatsLab/redalert/logger"
+	"github.com/TechCatsLab/redalert/protocol"
+	"github.com/TechCatsLab/redalert/ratelimit"
+)
+
+const (
+	defaultMulticastRate = 10 << 20 // bytes per second of multicast if Conf.Limit is 0
+	multicastLinger      = 2        // seconds receivers are quiet before multicast ends
+	repairHold           = 50       // ms a resent pack is not resent again
+)
+
+var (
+	// ErrNotMulticast address is not a multicast group
+	ErrNotMulticast = errors.New("Not a multicast address")
+	// ErrMulticastSecure multicast packets can't be encrypted
+	ErrMulticastSecure = errors.New("Multicast can't be encrypted")
+)
+
+// Report is the result of a receiver of multicast
+type Report struct {
+	Addr *net.UDPAddr
+	Err  error // nil if the file is received
+}
+
+// feedback is a NACK or report from a receiver
+type feedback struct {
+	addr   *net.UDPAddr
+	proto  protocol.Proto
+	orders []uint32
+}
+
+// multicaster send a file to a multicast group. Pack 0 is the request and
+// pack count+1 is the finish, so receivers ask for them as for file packs.
+type multicaster struct {
+	conn  *net.UDPConn
+	group *net.UDPAddr
+	rate  *ratelimit.Bucket
+
+	file  *os.File
+	room  int
+	count uint32
+	hash  hash.Hash
+	sum   []byte // MD5 of file, nil until all packs are sent once
+
+	request []byte
+	pack    protocol.Encode
+
+	next     uint32               // next pack to send for the first time
+	repairs  []uint32             // packs asked by receivers to resend
+	queued   map[uint32]bool      // packs in repairs
+	repaired map[uint32]time.Time // when packs are resent last
+
+	log logger.Logger
+}
+
+// Multicast send conf.FileName to multicast group conf.RemoteAddress once,
+// servers joined the group receive it together. A pack lost by a receiver is
+// resent to the group once the receiver asks for it, and every receiver
+// reports its result after the whole file is received. Multicast waits for
+// conf.Receivers reports, or until receivers are quiet if it's 0, and
+// returns the reports.
+func Multicast(conf *Conf) ([]Report, error) {
+	if conf.Secure {
+		return nil, ErrMulticastSecure
+	}
+
+	group, err := net.ResolveUDPAddr("udp", net.JoinHostPort(conf.RemoteAddress, conf.RemotePort))
+	if err != nil {
+		return nil, err
+	}
+
+	if !group.IP.IsMulticast() {
+		return nil, ErrNotMulticast
+	}
+
+	file, err := os.Open(conf.FileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	name := conf.Dest
+	if name == "" {
+		name = info.Name()
+	}
+
+	if len(name) > protocol.FirstPacketSize-protocol.FixedHeaderSize {
+		return nil, ErrNameTooLong
+	}
+
+	size := conf.PacketSize
+	if size < protocol.FirstPacketSize {
+		size = protocol.FirstPacketSize
+	}
+
+	if size > protocol.MaxPacketSize {
+		size = protocol.MaxPacketSize
+	}
+
+	limit := conf.Limit
+	if limit == 0 {
+		limit = defaultMulticastRate
+	}
+
+	conn, err := net.ListenUDP("udp", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	conn.SetReadBuffer(bufferSize)
+	conn.SetWriteBuffer(bufferSize)
+
+	m := &multicaster{
+		conn:     conn,
+		group:    group,
+		rate:     ratelimit.NewByteBucket(limit),
+		file:     file,
+		room:     size - protocol.FixedHeaderSize,
+		hash:     md5.New(),
+		pack:     protocol.Encode{Body: make([]byte, size)},
+		queued:   make(map[uint32]bool),
+		repaired: make(map[uint32]time.Time),
+		log:      logger.Or(conf.Log).With("group", group.String(), "file", conf.FileName),
+	}
+	m.count = uint32((info.Size() + int64(m.room) - 1) / int64(m.room))
+	m.pack.Buffer = bytes.NewBuffer(m.pack.Body)
+
+	m.request = make([]byte, protocol.FirstPacketSize)
+	request := protocol.Encode{Body: m.request, Buffer: bytes.NewBuffer(m.request)}
+	request.Marshal(&protocol.Proto{
+		HeaderType: protocol.HeaderRequestType,
+		HeaderSize: uint16(protocol.FixedHeaderSize + len(name)),
+		PackSize:   uint16(size),
+	})
+	copy(m.request[protocol.FileNameOffset:], name)
+
+	opts := protocol.Options{}
+	opts.SetUint64(protocol.OptionSize, uint64(info.Size()))
+	if err = opts.Marshal(m.request[protocol.FixedHeaderSize+len(name):]); err != nil {
+		return nil, err
+	}
+
+	return m.run(conf.Receivers)
+}
+
+// run send all packs, then resend packs asked by receivers and the finish
+// until receivers are done
+func (m *multicaster) run(receivers int) ([]Report, error) {
+	events := make(chan *feedback, 64)
+	go m.receive(events)
+
+	reports := make(map[string]*Report)
+	begin := time.Now()
+	last := begin // time of the last news from receivers or first send of a pack
+
+	for {
+		if len(m.repairs) > 0 || m.next <= m.count+1 {
+			select {
+			case e := <-events:
+				if m.handle(e, reports) {
+					last = time.Now()
+				}
+
+			default:
+				order, first := m.next, true
+				if len(m.repairs) > 0 {
+					order, first = m.repairs[0], false
+					m.repairs = m.repairs[1:]
+					delete(m.queued, order)
+					m.repaired[order] = time.Now()
+				} else {
+					m.next++
+					last = time.Now()
+				}
+
+				if err := m.send(order, first); err != nil {
+					return sortReports(reports), err
+				}
+			}
+			continue
+		}
+
+		if receivers > 0 && len(reports) >= receivers {
+			break
+		}
+
+		idle := time.Since(last)
+		if receivers == 0 && idle > multicastLinger*time.Second {
+			break
+		}
+
+		if idle > maxResend*resendInterval*time.Millisecond {
+			return sortReports(reports), ErrTimeout
+		}
+
+		select {
+		case e := <-events:
+			if m.handle(e, reports) {
+				last = time.Now()
+			}
+
+		case <-time.After(resendInterval * time.Millisecond):
+			if err := m.send(m.count+1, false); err != nil {
+				return sortReports(reports), err
+			}
+		}
+	}
+
+	m.log.Info("send file finish", "elapsed", time.Since(begin), "reports", len(reports))
+
+	return sortReports(reports), nil
+}
+
+// send send pack of order to the group, first if it's never sent before
+func (m *multicaster) send(order uint32, first bool) error {
+	packet := m.request
+
+	switch {
+	case order == m.count+1:
+		if m.sum == nil {
+			m.sum = m.hash.Sum(nil)
+		}
+
+		m.pack.Marshal(&protocol.Proto{
+			HeaderType: protocol.HeaderFileFinishType,
+			HeaderSize: protocol.FixedHeaderSize,
+			PackSize:   md5.Size,
+			PackOrder:  order,
+		})
+		packet = m.pack.Body[:protocol.FixedHeaderSize+copy(m.pack.Body[protocol.FixedHeaderSize:], m.sum)]
+
+	case order > 0:
+		body := m.pack.Body[protocol.FixedHeaderSize:]
+		num, err := m.file.ReadAt(body, int64(order-1)*int64(m.room))
+		if err != nil && err != io.EOF {
+			return err
+		}
+
+		// packs are sent in order for the first time
+		if first {
+			m.hash.Write(body[:num])
+		}
+
+		m.pack.Marshal(&protocol.Proto{
+			HeaderType: protocol.HeaderFileType,
+			HeaderSize: protocol.FixedHeaderSize,
+			PackSize:   uint16(num),
+			PackOrder:  order,
+		})
+		packet = m.pack.Body[:protocol.FixedHeaderSize+num]
+	}
+
+	m.rate.WaitN(len(packet))
+	_, err := m.conn.WriteToUDP(packet, m.group)
+
+	return err
+}
+
+// handle queue packs asked by a receiver, or keep its report. It tells if
+// there is news, a pack to resend or a new report.
+func (m *multicaster) handle(e *feedback, reports map[string]*Report) bool {
+	if e.proto.HeaderType == protocol.HeaderReportType {
+		if _, ok := reports[e.addr.String()]; ok {
+			return false
+		}
+
+		report := &Report{Addr: e.addr, Err: replyError(e.proto.PackOrder)}
+		reports[e.addr.String()] = report
+		m.log.Info("receive report", "remote", e.addr.String(), "err", report.Err)
+
+		return true
+	}
+
+	news := false
+	for _, order := range e.orders {
+		if order >= m.next || m.queued[order] || time.Since(m.repaired[order]) < repairHold*time.Millisecond {
+			continue
+		}
+
+		m.queued[order] = true
+		m.repairs = append(m.repairs, order)
+		news = true
+	}
+
+	return news
+}
+
+// receive read NACKs and reports from receivers until conn is closed
+func (m *multicaster) receive(events chan<- *feedback) {
+	buf := make([]byte, protocol.MaxPacketSize)
+
+	for {
+		num, addr, err := m.conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+
+		if num < protocol.FixedHeaderSize {
+			continue
+		}
+
+		e := &feedback{addr: addr}
+		pack := protocol.Encode{Body: buf, Buffer: bytes.NewBuffer(buf)}
+		pack.Unmarshal(&e.proto)
+
+		switch e.proto.HeaderType {
+		case protocol.HeaderNackType:
+			body := buf[protocol.FixedHeaderSize:num]
+			for i := 0; i+4 <= len(body) && i < int(e.proto.PackSize); i += 4 {
+				e.orders = append(e.orders, binary.BigEndian.Uint32(body[i:]))
+			}
+
+		case protocol.HeaderReportType:
+
+		default:
+			continue
+		}
+
+		events <- e
+	}
+}
+
+// replyError return error of reply from server
+func replyError(reply uint32) error {
+	switch reply {
+	case 0:
+		return nil
+	case protocol.ReplyUnauthorized:
+		return auth.ErrUnauthorized
+	case protocol.ReplyForbidden:
+		return ErrForbidden
+	}
+
+	return ErrFromServer
+}
+
+// sortReports return reports by address
+func sortReports(reports map[string]*Report) []Report {
+	list := make([]Report, 0, len(reports))
+	for _, r := range reports {
+		list = append(list, *r)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Addr.String() < list[j].Addr.String()
+	})
+
+	return list
+}
@@ -0,0 +1,131 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package client
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"time"
+
+	"github.com/TechCatsLab/redalert/logger"
+	"github.com/TechCatsLab/redalert/protocol"
+)
+
+const (
+	probeInterval = 150 // ms to wait for reply of a probe
+	probeTries    = 2
+)
+
+// packetSize return the largest packet size to send through conn, conn is
+// sock with encryption and limit. A udp path is probed, a unixgram socket
+// takes MaxPacketSize.
+func packetSize(conn, sock net.Conn, log logger.Logger) int {
+	if udpConn, ok := sock.(*net.UDPConn); ok {
+		return probe(conn, udpConn, log)
+	}
+
+	return protocol.MaxPacketSize
+}
+
+// probe find the largest packet size from FirstPacketSize to MaxPacketSize
+// which gets through conn to server without fragmenting, by padded probe
+// packets. udpConn is the socket under conn. It returns FirstPacketSize if
+// fragmenting can't be disabled or server doesn't answer probes.
+func probe(conn net.Conn, udpConn *net.UDPConn, log logger.Logger) int {
+	restore, err := dontFragment(udpConn)
+	if err != nil {
+		log.Warn("can't disable fragmenting", "err", err)
+		return protocol.FirstPacketSize
+	}
+	defer restore()
+	defer conn.SetReadDeadline(time.Time{})
+
+	buf := make([]byte, protocol.MaxPacketSize)
+	reply := make([]byte, protocol.FirstPacketSize)
+
+	lo, hi := protocol.FirstPacketSize, protocol.MaxPacketSize
+	if !sendProbe(conn, buf[:lo], reply) {
+		log.Warn("no reply to probe")
+		return lo
+	}
+
+	// most paths are either loopback or jumbo, try max first
+	if sendProbe(conn, buf[:hi], reply) {
+		lo = hi
+	}
+
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if sendProbe(conn, buf[:mid], reply) {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+
+	log.Debug("probe packet size", "size", lo)
+
+	return lo
+}
+
+// sendProbe send probe packet p and tell if server replies with its size
+func sendProbe(conn net.Conn, p []byte, reply []byte) bool {
+	probe := protocol.Encode{
+		Body: p,
+	}
+	probe.Buffer = bytes.NewBuffer(probe.Body)
+	probe.Marshal(&protocol.Proto{
+		HeaderType: protocol.HeaderProbeType,
+		HeaderSize: protocol.FixedHeaderSize,
+		PackSize:   uint16(len(p) - protocol.FixedHeaderSize),
+	})
+
+	for try := 0; try < probeTries; try++ {
+		// too large for local interface
+		if _, err := conn.Write(p); err != nil {
+			return false
+		}
+
+		conn.SetReadDeadline(time.Now().Add(probeInterval * time.Millisecond))
+		for {
+			n, err := conn.Read(reply)
+			if err != nil {
+				break
+			}
+
+			if n == protocol.ReplySize && binary.BigEndian.Uint32(reply) == uint32(len(p)) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,122 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package client
+
+import (
+	"net"
+	"time"
+
+	"github.com/TechCatsLab/redalert/protocol"
+	"github.com/TechCatsLab/redalert/udp/secure"
+)
+
+// sealedConn seals every packet written to the conn and opens every packet
+// read from it, packets failed to open are dropped.
+type sealedConn struct {
+	net.Conn
+	session *secure.Session
+	buf     []byte
+}
+
+func (c *sealedConn) Write(b []byte) (int, error) {
+	if _, err := c.Conn.Write(c.session.Seal(b)); err != nil {
+		return 0, err
+	}
+
+	return len(b), nil
+}
+
+func (c *sealedConn) Read(b []byte) (int, error) {
+	for {
+		n, err := c.Conn.Read(c.buf)
+		if err != nil {
+			return 0, err
+		}
+
+		packet, err := c.session.Open(c.buf[:n])
+		if err != nil {
+			continue
+		}
+
+		return copy(b, packet), nil
+	}
+}
+
+// handshake exchange keys with server, and return a conn of the encrypted session
+func handshake(conn net.Conn, psk []byte) (net.Conn, error) {
+	priv, err := secure.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	hello := secure.Hello(priv.PublicKey().Bytes())
+	buf := make([]byte, protocol.FirstPacketSize)
+
+	defer conn.SetReadDeadline(time.Time{})
+
+	for resend := 0; resend <= maxResend; resend++ {
+		if _, err = conn.Write(hello); err != nil {
+			return nil, err
+		}
+
+		conn.SetReadDeadline(time.Now().Add(resendInterval * time.Millisecond))
+
+		n, err := conn.Read(buf)
+		if err != nil {
+			if e, ok := err.(net.Error); ok && e.Timeout() {
+				continue
+			}
+
+			return nil, err
+		}
+
+		if n == protocol.ReplySize {
+			return nil, ErrFromServer
+		}
+
+		pub, id, err := secure.ParseHelloReply(buf[:n])
+		if err != nil {
+			continue
+		}
+
+		session, err := secure.NewSession(secure.Client, id, priv, pub, psk)
+		if err != nil {
+			return nil, err
+		}
+
+		return &sealedConn{
+			Conn:    conn,
+			session: session,
+			buf:     make([]byte, protocol.MaxPacketSize+secure.Overhead),
+		}, nil
+	}
+
+	return nil, ErrTimeout
+}
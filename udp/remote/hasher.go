@@ -0,0 +1,140 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package remote
+
+import (
+	"hash"
+	"sync"
+
+	"github.com/TechCatsLab/redalert/sparse"
+)
+
+// zeroChunk is how many zeros are hashed before checking if the transfer is
+// closed
+const zeroChunk = 1 << 20
+
+// hashJob content to hash, zeros of length n if data is nil
+type hashJob struct {
+	data []byte
+	n    int64
+}
+
+// hasher hash content of a transfer in order, zeros of a hole are hashed
+// off the receive loop since a hole may be as large as the file, content
+// received meanwhile is queued behind them
+type hasher struct {
+	mu      sync.Mutex
+	hash    hash.Hash
+	queue   []hashJob
+	running bool // a goroutine is hashing the queue
+	stopped bool // transfer is closed, the queue is dropped
+	done    func()
+}
+
+// write hash p, or queue a copy of it if zeros are being hashed
+func (h *hasher) write(p []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.running {
+		h.queue = append(h.queue, hashJob{data: append([]byte(nil), p...)})
+		return
+	}
+
+	h.hash.Write(p)
+}
+
+// zeros hash n zeros in background, done is called once the queue is hashed
+func (h *hasher) zeros(n int64, done func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.queue = append(h.queue, hashJob{n: n})
+	h.done = done
+	if !h.running {
+		h.running = true
+		go h.run()
+	}
+}
+
+// busy tell if the queue is being hashed
+func (h *hasher) busy() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.running
+}
+
+// stop drop the queue, done isn't called
+func (h *hasher) stop() {
+	h.mu.Lock()
+	h.stopped = true
+	h.queue = nil
+	h.mu.Unlock()
+}
+
+func (h *hasher) run() {
+	for {
+		h.mu.Lock()
+		if h.stopped || len(h.queue) == 0 {
+			h.running = false
+			done := h.done
+			if h.stopped {
+				done = nil
+			}
+			h.mu.Unlock()
+
+			if done != nil {
+				done()
+			}
+			return
+		}
+
+		job := &h.queue[0]
+		if job.data != nil {
+			h.hash.Write(job.data)
+			h.queue = h.queue[1:]
+			h.mu.Unlock()
+			continue
+		}
+
+		n := job.n
+		if n > zeroChunk {
+			n = zeroChunk
+		}
+		job.n -= n
+		if job.n == 0 {
+			h.queue = h.queue[1:]
+		}
+		h.mu.Unlock()
+
+		sparse.WriteZeros(h.hash, n)
+	}
+}
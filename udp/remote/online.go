@@ -32,30 +32,63 @@ package remote
 import (
 	"crypto/md5"
 	"errors"
-	"fmt"
 	"hash"
 	"net"
-	"os"
+	"sync"
 	"time"
+
+	"github.com/TechCatsLab/redalert/codec"
+	"github.com/TechCatsLab/redalert/fec"
+	"github.com/TechCatsLab/redalert/logger"
+	"github.com/TechCatsLab/redalert/metrics"
+	"github.com/TechCatsLab/redalert/policy"
+	"github.com/TechCatsLab/redalert/ratelimit"
+	"github.com/TechCatsLab/redalert/storage"
 )
 
+// idleTimeout is how long a remote may be quiet before its transfer is
+// aborted, a client resends a lost pack every 500ms, so it's long enough for
+// many losses in a row on a lossy path
+const idleTimeout = 10 * time.Second
+
 // Service expose interface of RemoteAddrTable
 var (
 	Service    *remoteAddrTable
 	errTimeOut = errors.New("receive time out")
+	// ErrNotExists error for remote not in table
+	ErrNotExists = errors.New("Remote not exists")
+	// ErrHashNotMatch error for hash from client not match with hash which calculated by server
+	ErrHashNotMatch = errors.New("hash value not match")
 )
 
 // Remote storage remote client info
 type Remote struct {
 	FileName  string
-	File      *os.File
+	File      storage.Object
+	Offset    int64
 	PackCount uint32
 	Timer     *time.Timer
 	Hash      hash.Hash
+	Limit     int64             // Max size of file, no limit if 0
+	Size      int64             // Size of file declared by request, -1 if unknown
+	Rate      *ratelimit.Bucket // Bandwidth of client, no limit if nil
+	Codec     codec.Codec       // Codec of packs, nil if not compressed
+	FEC       *fec.Decoder      // FEC groups of packs, nil if not used
+	Transfer  *metrics.Transfer // Count file received in metrics, done on Close
+	Log       logger.Logger     // Logger with fields of the transfer, default to logger.Default()
+
+	sum *hasher
+}
+
+// Busy tell if zeros of a hole are being hashed, packs of the remote are
+// dropped meanwhile and resent by the client
+func (rem *Remote) Busy() bool {
+	return rem.sum.busy()
 }
 
 // RemoteAddrTable manege remote client address and it's transformation info
 type remoteAddrTable struct {
+	mu     sync.Mutex
 	remote map[string]*Remote
 }
 
@@ -65,64 +98,121 @@ func init() {
 	}
 }
 
-// OnStartTransfer storage Remote for new client
-func (r *remoteAddrTable) OnStartTransfer(filename string, file *os.File, remote *net.UDPAddr) {
-	key := remote.IP.String() + ":" + string(remote.Port)
+// OnStartTransfer storage Remote for new client and return it
+func (r *remoteAddrTable) OnStartTransfer(filename string, file storage.Object, remote net.Addr) *Remote {
 	rem := Remote{
 		FileName: filename,
 		File:     file,
-		Timer: time.AfterFunc(2*time.Second, func() {
+		Timer: time.AfterFunc(idleTimeout, func() {
 			r.Close(remote, errTimeOut)
 		}),
 		Hash: md5.New(),
+		Size: -1,
 	}
+	rem.sum = &hasher{hash: rem.Hash}
+
+	r.mu.Lock()
+	r.remote[remote.String()] = &rem
+	r.mu.Unlock()
 
-	r.remote[key] = &rem
-	fmt.Printf("[OnStartTransfer] create a table %v \n", remote)
+	return &rem
 }
 
 // GetRemote return *Remote and true if exists
-func (r *remoteAddrTable) GetRemote(rmt *net.UDPAddr) (*Remote, bool) {
-	key := rmt.IP.String() + ":" + string(rmt.Port)
-	fmt.Printf("[GetRemote] quering %v \n", rmt)
-	rem, ok := r.remote[key]
+func (r *remoteAddrTable) GetRemote(rmt net.Addr) (*Remote, bool) {
+	r.mu.Lock()
+	rem, ok := r.remote[rmt.String()]
+	r.mu.Unlock()
 
 	return rem, ok
 }
 
 // Update update timer and count when receive success
-func (r *remoteAddrTable) Update(remote *net.UDPAddr, pack []byte) error {
-	key := remote.IP.String() + ":" + string(remote.Port)
-	rem, _ := r.remote[key]
+func (r *remoteAddrTable) Update(remote net.Addr, pack []byte) error {
+	rem, ok := r.GetRemote(remote)
+	if !ok {
+		return nil
+	}
 
-	rem.Timer.Reset(2 * time.Second)
+	rem.Timer.Reset(idleTimeout)
 	if len(pack) == 0 {
 		return nil
 	}
 	rem.PackCount++
-	_, err := rem.Hash.Write(pack)
-	if err != nil {
-		return err
+	rem.sum.write(pack)
+
+	logger.Or(rem.Log).Debug("receive pack", "count", rem.PackCount)
+
+	return nil
+}
+
+// UpdateHole count a hole of n bytes, its zeros are hashed in background
+// and the timer stops until they are
+func (r *remoteAddrTable) UpdateHole(remote net.Addr, n int64) error {
+	rem, ok := r.GetRemote(remote)
+	if !ok {
+		return nil
 	}
 
-	fmt.Printf("[Update] now count is %d \n", rem.PackCount)
+	rem.Timer.Stop()
+	rem.PackCount++
+
+	rem.sum.zeros(n, func() {
+		if cur, ok := r.GetRemote(remote); ok && cur == rem {
+			rem.Timer.Reset(idleTimeout)
+		}
+	})
 
 	return nil
 }
 
-// Close file and delete map
-func (r *remoteAddrTable) Close(remote *net.UDPAddr, err error) {
-	key := remote.IP.String() + ":" + string(remote.Port)
-	fmt.Printf("[Close] remote %v with error: %v \n", remote, err)
+// Close commit file when err is nil, otherwise abort it, and delete map, it
+// returns nil if file is committed
+func (r *remoteAddrTable) Close(remote net.Addr, err error) error {
+	key := remote.String()
+
+	r.mu.Lock()
 	rem, ok := r.remote[key]
+	delete(r.remote, key)
+	r.mu.Unlock()
 	if !ok {
-		return
+		return ErrNotExists
 	}
 
-	rem.File.Close()
-	delete(r.remote, key)
+	log := logger.Or(rem.Log)
+
 	rem.Timer.Stop()
+	rem.sum.stop()
 	if err != nil {
-		os.Remove(rem.FileName)
+		log.Error("abort file", "err", err)
+
+		rem.File.Abort()
+		rem.Transfer.Done(result(err))
+		return err
+	}
+
+	if err = rem.File.Commit(); err != nil {
+		log.Error("commit file error", "err", err)
+	} else {
+		log.Info("receive file finish", "size", rem.Offset)
+	}
+	rem.Transfer.Done(result(err))
+
+	return err
+}
+
+// result return result of a transfer closed with err in metrics
+func result(err error) string {
+	switch {
+	case err == nil:
+		return metrics.ResultOK
+	case err == errTimeOut:
+		return metrics.ResultTimeout
+	case err == ErrHashNotMatch:
+		return metrics.ResultHashMismatch
+	case policy.Denied(err):
+		return metrics.ResultDenied
 	}
+
+	return metrics.ResultError
 }
@@ -0,0 +1,289 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package secure
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"sync"
+
+	"github.com/TechCatsLab/redalert/protocol"
+)
+
+// Role of a session, it decides the nonce prefix so both directions never
+// share a nonce under the same key.
+type Role byte
+
+const (
+	// Client role of the side which sends hello
+	Client Role = 1
+	// Server role of the side which replies hello
+	Server Role = 2
+)
+
+const (
+	// KeySize size of X25519 public key
+	KeySize = 32
+	// IDSize size of session ID
+	IDSize = 8
+	// SeqSize size of packet sequence
+	SeqSize = 8
+	// HeaderSize size of sealed packet header, it's authenticated but not encrypted
+	HeaderSize = 1 + IDSize + SeqSize
+	// Overhead sealed packet is larger than the plain one by Overhead
+	Overhead = HeaderSize + 16
+	// Window number of sequences below the largest received which are still
+	// opened once, packets sealed or delivered out of order within it are kept
+	Window = 64
+
+	helloSize      = protocol.FixedHeaderSize + KeySize
+	helloReplySize = protocol.FixedHeaderSize + KeySize + IDSize
+	label          = "redalert udp session"
+)
+
+var (
+	// ErrForged packet not sealed by the session
+	ErrForged = errors.New("Packet authentication failed")
+	// ErrReplayed packet sequence has been received
+	ErrReplayed = errors.New("Packet replayed")
+	// ErrInvalidHello hello packet malformed
+	ErrInvalidHello = errors.New("Invalid hello packet")
+)
+
+// Session seals and opens packets of an encrypted UDP session. Every sealed
+// packet carries the session ID and a sequence, both bound to the packet by
+// AEAD, and a sequence is only opened once, if it's within Window of the
+// largest one received.
+type Session struct {
+	ID   uint64
+	role Role
+	aead cipher.AEAD
+
+	mu       sync.Mutex
+	sent     uint64
+	received uint64 // largest sequence received
+	seen     uint64 // bit i is set if sequence received-i is received
+}
+
+// GenerateKey generate a X25519 key pair for hello
+func GenerateKey() (*ecdh.PrivateKey, error) {
+	return ecdh.X25519().GenerateKey(rand.Reader)
+}
+
+// NewID generate a random session ID
+func NewID() (uint64, error) {
+	var b [IDSize]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return 0, err
+	}
+
+	return binary.BigEndian.Uint64(b[:]), nil
+}
+
+// NewSession derive session key from the key exchange, psk is optional and
+// mixed into the key so only peers sharing it can talk.
+func NewSession(role Role, id uint64, priv *ecdh.PrivateKey, peer, psk []byte) (*Session, error) {
+	peerKey, err := ecdh.X25519().NewPublicKey(peer)
+	if err != nil {
+		return nil, err
+	}
+
+	shared, err := priv.ECDH(peerKey)
+	if err != nil {
+		return nil, err
+	}
+
+	clientKey, serverKey := priv.PublicKey().Bytes(), peer
+	if role == Server {
+		clientKey, serverKey = serverKey, clientKey
+	}
+
+	mac := hmac.New(sha256.New, psk)
+	mac.Write([]byte(label))
+	mac.Write(shared)
+	mac.Write(clientKey)
+	mac.Write(serverKey)
+	binary.Write(mac, binary.BigEndian, id)
+
+	block, err := aes.NewCipher(mac.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Session{
+		ID:   id,
+		role: role,
+		aead: aead,
+	}, nil
+}
+
+func (s *Session) nonce(role Role, seq uint64) []byte {
+	nonce := make([]byte, s.aead.NonceSize())
+	nonce[0] = byte(role)
+	binary.BigEndian.PutUint64(nonce[len(nonce)-SeqSize:], seq)
+
+	return nonce
+}
+
+// Seal return a sealed copy of packet
+func (s *Session) Seal(packet []byte) []byte {
+	s.mu.Lock()
+	s.sent++
+	seq := s.sent
+	s.mu.Unlock()
+
+	sealed := make([]byte, HeaderSize, HeaderSize+len(packet)+s.aead.Overhead())
+	sealed[0] = protocol.HeaderSealedType
+	binary.BigEndian.PutUint64(sealed[1:], s.ID)
+	binary.BigEndian.PutUint64(sealed[1+IDSize:], seq)
+
+	return s.aead.Seal(sealed, s.nonce(s.role, seq), packet, sealed[:HeaderSize])
+}
+
+// Open authenticate and decrypt sealed in place, return the plain packet
+func (s *Session) Open(sealed []byte) ([]byte, error) {
+	id, ok := ID(sealed)
+	if !ok || id != s.ID || len(sealed) < Overhead {
+		return nil, ErrForged
+	}
+
+	peer := Server
+	if s.role == Server {
+		peer = Client
+	}
+
+	seq := binary.BigEndian.Uint64(sealed[1+IDSize:])
+	packet, err := s.aead.Open(sealed[HeaderSize:HeaderSize], s.nonce(peer, seq), sealed[HeaderSize:], sealed[:HeaderSize])
+	if err != nil {
+		return nil, ErrForged
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.accept(seq) {
+		return nil, ErrReplayed
+	}
+
+	return packet, nil
+}
+
+// accept mark seq received, it reports false if seq has been received or is
+// too old to tell. It must be called with mu held.
+func (s *Session) accept(seq uint64) bool {
+	if seq > s.received {
+		if shift := seq - s.received; shift < Window {
+			s.seen = s.seen<<shift | 1
+		} else {
+			s.seen = 1
+		}
+		s.received = seq
+
+		return true
+	}
+
+	back := s.received - seq
+	if back >= Window || s.seen&(1<<back) != 0 {
+		return false
+	}
+	s.seen |= 1 << back
+
+	return true
+}
+
+// ID return session ID of a sealed packet
+func ID(sealed []byte) (uint64, bool) {
+	if len(sealed) < HeaderSize || sealed[0] != protocol.HeaderSealedType {
+		return 0, false
+	}
+
+	return binary.BigEndian.Uint64(sealed[1:]), true
+}
+
+func marshalHello(size int, pub []byte) []byte {
+	hello := protocol.Encode{
+		Body: make([]byte, size),
+	}
+	hello.Buffer = bytes.NewBuffer(hello.Body)
+	hello.Marshal(&protocol.Proto{
+		HeaderType: protocol.HeaderHelloType,
+		HeaderSize: protocol.FixedHeaderSize,
+		PackSize:   uint16(size - protocol.FixedHeaderSize),
+	})
+	copy(hello.Body[protocol.FixedHeaderSize:], pub)
+
+	return hello.Body
+}
+
+// Hello build hello packet of client
+func Hello(pub []byte) []byte {
+	return marshalHello(helloSize, pub)
+}
+
+// ParseHello return public key of client in hello packet
+func ParseHello(hello []byte) ([]byte, error) {
+	if len(hello) < helloSize || hello[0] != protocol.HeaderHelloType {
+		return nil, ErrInvalidHello
+	}
+
+	return hello[protocol.FixedHeaderSize:helloSize], nil
+}
+
+// HelloReply build hello packet of server
+func HelloReply(pub []byte, id uint64) []byte {
+	reply := marshalHello(helloReplySize, pub)
+	binary.BigEndian.PutUint64(reply[protocol.FixedHeaderSize+KeySize:], id)
+
+	return reply
+}
+
+// ParseHelloReply return public key of server and session ID in hello packet
+func ParseHelloReply(reply []byte) ([]byte, uint64, error) {
+	if len(reply) < helloReplySize || reply[0] != protocol.HeaderHelloType {
+		return nil, 0, ErrInvalidHello
+	}
+
+	pub := reply[protocol.FixedHeaderSize : protocol.FixedHeaderSize+KeySize]
+	id := binary.BigEndian.Uint64(reply[protocol.FixedHeaderSize+KeySize:])
+
+	return pub, id, nil
+}
@@ -0,0 +1,111 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package secure_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/TechCatsLab/redalert/udp/secure"
+)
+
+// pair return sessions of a client and a server which share a key
+func pair(t *testing.T) (*secure.Session, *secure.Session) {
+	clientKey, err := secure.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverKey, err := secure.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := secure.NewSession(secure.Client, 1, clientKey, serverKey.PublicKey().Bytes(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := secure.NewSession(secure.Server, 1, serverKey, clientKey.PublicKey().Bytes(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return client, server
+}
+
+func TestOpenOrder(t *testing.T) {
+	cases := []struct {
+		name  string
+		order []int  // index of sealed packets in the order they're opened
+		ok    []bool // if each one is opened
+	}{
+		{"in order", []int{0, 1, 2}, []bool{true, true, true}},
+		{"reordered", []int{2, 0, 1}, []bool{true, true, true}},
+		{"replayed", []int{0, 1, 0}, []bool{true, true, false}},
+		{"replayed reordered", []int{1, 0, 1, 0}, []bool{true, true, false, false}},
+		{"out of window", []int{secure.Window, 0}, []bool{true, false}},
+		{"edge of window", []int{secure.Window, 1}, []bool{true, true}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			client, server := pair(t)
+
+			sealed := make([][]byte, secure.Window+1)
+			for i := range sealed {
+				sealed[i] = client.Seal([]byte{byte(i)})
+			}
+
+			for i, n := range c.order {
+				packet, err := server.Open(append([]byte(nil), sealed[n]...))
+				if ok := err == nil; ok != c.ok[i] {
+					t.Fatalf("open %d: %v", n, err)
+				}
+				if err == nil && !bytes.Equal(packet, []byte{byte(n)}) {
+					t.Fatalf("open %d: got %v", n, packet)
+				}
+			}
+		})
+	}
+}
+
+func TestOpenForged(t *testing.T) {
+	client, server := pair(t)
+
+	sealed := client.Seal([]byte("pack"))
+	sealed[len(sealed)-1] ^= 0xff
+	if _, err := server.Open(sealed); err != secure.ErrForged {
+		t.Fatalf("got %v, want %v", err, secure.ErrForged)
+	}
+
+	// a packet of the server isn't opened as one of the client
+	if _, err := server.Open(server.Seal([]byte("pack"))); err != secure.ErrForged {
+		t.Fatalf("got %v, want %v", err, secure.ErrForged)
+	}
+}
@@ -0,0 +1,157 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package server
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"time"
+
+	"github.com/TechCatsLab/redalert/auth"
+	"github.com/TechCatsLab/redalert/protocol"
+)
+
+const (
+	challengeTimeout = 30 * time.Second
+)
+
+// challenge is an auth challenge sent to remote for its request, the request
+// is handled once remote answers it
+type challenge struct {
+	nonce   []byte
+	request []byte
+	reply   []byte
+	timer   *time.Timer
+	// answered is set once remote answers it right
+	answered bool
+}
+
+// challenge challenge the remote of request pack
+func (c *Service) challenge(pack *Packet) {
+	key := pack.Remote.String()
+	request := pack.Body[:pack.Size]
+
+	c.challengeMu.Lock()
+	ch, ok := c.challenges[key]
+	c.challengeMu.Unlock()
+
+	// repeated request, the challenge is lost
+	if ok && bytes.Equal(ch.request, request) {
+		c.Send(ch.reply, pack.Remote)
+		return
+	}
+
+	nonce, err := auth.NewChallenge()
+	if err != nil {
+		c.log.Error("generate challenge error", "err", err)
+		return
+	}
+
+	ch = &challenge{
+		nonce:   nonce,
+		request: append([]byte(nil), request...),
+		reply:   make([]byte, protocol.ReplySize+auth.ChallengeSize),
+	}
+	binary.BigEndian.PutUint32(ch.reply, protocol.ReplyChallenge)
+	copy(ch.reply[protocol.ReplySize:], nonce)
+
+	c.challengeMu.Lock()
+	if old, ok := c.challenges[key]; ok {
+		old.timer.Stop()
+	}
+	c.challenges[key] = ch
+	ch.timer = time.AfterFunc(challengeTimeout, func() {
+		c.challengeMu.Lock()
+		if c.challenges[key] == ch {
+			delete(c.challenges, key)
+		}
+		c.challengeMu.Unlock()
+	})
+	c.challengeMu.Unlock()
+
+	c.Send(ch.reply, pack.Remote)
+}
+
+// onAuth verify answer of remote and handle its request. The challenge is
+// kept until timeout, so a repeated answer handles the request again.
+func (c *Service) onAuth(pack *Packet) {
+	key := pack.Remote.String()
+
+	c.challengeMu.Lock()
+	ch, ok := c.challenges[key]
+	c.challengeMu.Unlock()
+
+	if !ok {
+		c.log.Warn("no challenge", "remote", pack.Remote.String())
+		return
+	}
+
+	id, mac, err := auth.Parse(pack.Body[:pack.Size])
+	if err == nil && !c.conf.Auth.Verify(id, ch.nonce, ch.request, mac) {
+		err = auth.ErrUnauthorized
+	}
+
+	if err != nil {
+		c.challengeMu.Lock()
+		if c.challenges[key] == ch {
+			ch.timer.Stop()
+			delete(c.challenges, key)
+		}
+		c.challengeMu.Unlock()
+
+		c.log.Warn("reject client", "remote", pack.Remote.String(), "err", err)
+
+		unauthorized := make([]byte, protocol.ReplySize)
+		binary.BigEndian.PutUint32(unauthorized, protocol.ReplyUnauthorized)
+		c.Send(unauthorized, pack.Remote)
+		return
+	}
+
+	c.log.Debug("authenticate client", "remote", pack.Remote.String(), "client", id)
+
+	c.challengeMu.Lock()
+	ch.answered = true
+	c.challengeMu.Unlock()
+
+	pack.Size = copy(pack.Body, ch.request)
+	pack.client = id
+	c.dispatch(pack, pack.handle())
+}
+
+// awaitingAuth report if remote is challenged and hasn't answered yet
+func (c *Service) awaitingAuth(addr net.Addr) bool {
+	c.challengeMu.Lock()
+	defer c.challengeMu.Unlock()
+
+	ch, ok := c.challenges[addr.String()]
+
+	return ok && !ch.answered
+}
@@ -0,0 +1,101 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package server
+
+import (
+	"encoding/binary"
+
+	"github.com/TechCatsLab/redalert/protocol"
+	"github.com/TechCatsLab/redalert/udp/remote"
+)
+
+// grouped tell if p is a pack of a FEC group, which is held until its group
+// is complete
+func (p *Packet) grouped() bool {
+	switch p.Body[0] {
+	case protocol.HeaderFileType, protocol.HeaderHoleType, protocol.HeaderParityType:
+	default:
+		return false
+	}
+
+	rem, ok := remote.Service.GetRemote(p.Remote)
+
+	return ok && rem.FEC != nil
+}
+
+// onGroup add pack to its FEC group, packs of the group are handled in
+// order once it's complete, a lost pack is recovered from parity
+func (c *Service) onGroup(pack *Packet) {
+	rem, ok := remote.Service.GetRemote(pack.Remote)
+	if !ok || pack.Size < protocol.FixedHeaderSize {
+		return
+	}
+
+	// packs are resent once zeros of a hole are hashed
+	if rem.Busy() {
+		return
+	}
+
+	// timer of remote is reset by packs being held
+	remote.Service.Update(pack.Remote, nil)
+
+	body := pack.Body[:pack.Size]
+	order := binary.BigEndian.Uint32(body[protocol.PackOrderOffset:])
+	size := protocol.FixedHeaderSize + int(binary.BigEndian.Uint16(body[protocol.PackSizeOffset:]))
+	if size > len(body) {
+		return
+	}
+	body = body[:size]
+
+	var complete bool
+	switch {
+	case order < rem.FEC.Start():
+		// the last pack of the group handled, its reply is lost
+		if order == rem.FEC.Start()-1 && body[0] != protocol.HeaderParityType {
+			c.dispatch(pack, pack.handle())
+		}
+		return
+
+	case body[0] == protocol.HeaderParityType:
+		complete = rem.FEC.AddParity(order, body[protocol.FixedHeaderSize:])
+
+	default:
+		complete = rem.FEC.Add(order, body)
+	}
+
+	if !complete {
+		return
+	}
+
+	for _, p := range rem.FEC.Packs() {
+		pack.Size = copy(pack.Body, p)
+		c.dispatch(pack, pack.handle())
+	}
+}
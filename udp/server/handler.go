@@ -30,37 +30,37 @@
 package server
 
 import (
-	"fmt"
 	"net"
-	"time"
 
+	"github.com/TechCatsLab/redalert/logger"
 	"github.com/TechCatsLab/redalert/protocol"
 	"github.com/TechCatsLab/redalert/udp/remote"
 )
 
 // Handler represent operations by UDP service
 type Handler interface {
-	OnError(error, *net.UDPAddr)
+	OnError(error, net.Addr)
 	OnPacket(*Packet) error
 	OnClose(*Service) error
 }
 
 // Provider provide service
-type Provider struct{}
+type Provider struct {
+	log logger.Logger
+}
 
 var nilPack = make([]byte, 0)
 
 // OnError handle when encounters error
-func (sp *Provider) OnError(err error, addr *net.UDPAddr) {
-	fmt.Printf("[OnError] crash with error %v \n", err)
-	time.Sleep(1 * time.Second)
-	remote.Service.Close(addr, err)
+func (sp *Provider) OnError(err error, addr net.Addr) {
+	logger.Or(sp.log).Error("receive error", "err", err)
+	if addr != nil {
+		remote.Service.Close(addr, err)
+	}
 }
 
 // OnPacket update client info in the online table according to pack HeaderType
 func (sp *Provider) OnPacket(pack *Packet) error {
-	fmt.Printf("[OnPacket] pack type is %d, %d, %d \n", pack.proto.HeaderType, len(pack.Body), cap(pack.Body))
-
 	if pack.proto.HeaderType == protocol.HeaderFileFinishType || pack.Repeat == 1 {
 		return nil
 	}
@@ -72,18 +72,26 @@ func (sp *Provider) OnPacket(pack *Packet) error {
 	}
 
 	if pack.proto.HeaderType == protocol.HeaderFileType {
-		err := remote.Service.Update(pack.Remote, pack.Body[protocol.FixedHeaderSize:protocol.FixedHeaderSize+pack.proto.PackSize])
+		err := remote.Service.Update(pack.Remote, pack.data)
 		if err != nil {
 			return err
 		}
 	}
 
+	// a hole is replied once it's hashed, when the client resends it
+	if pack.proto.HeaderType == protocol.HeaderHoleType {
+		if err := remote.Service.UpdateHole(pack.Remote, pack.hole); err != nil {
+			return err
+		}
+
+		return errPending
+	}
+
 	return nil
 }
 
 // OnClose close server
 func (sp *Provider) OnClose(s *Service) error {
-	time.Sleep(1 * time.Second)
 	s.Close()
 
 	return nil
@@ -0,0 +1,388 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package server
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"net"
+	"time"
+
+	"github.com/TechCatsLab/redalert/logger"
+	"github.com/TechCatsLab/redalert/policy"
+	"github.com/TechCatsLab/redalert/protocol"
+	"github.com/TechCatsLab/redalert/storage"
+)
+
+const (
+	nackInterval = 100 * time.Millisecond // how often receivers ask for lost packs
+	groupTimeout = 10 * time.Second       // a multicast sender is gone
+	maxPending   = 4096                   // packs received ahead of a lost one
+	maxNack      = (protocol.FirstPacketSize - protocol.FixedHeaderSize) / 4
+)
+
+// ErrNotMulticast address is not a multicast group
+var ErrNotMulticast = errors.New("Not a multicast address")
+
+// groupSession is a file received from a multicast sender. Pack 0 is the
+// request and pack count+1 is the finish, packs after a lost one are kept
+// until it's resent, so the file is written in order.
+type groupSession struct {
+	addr   *net.UDPAddr
+	active time.Time // last packet from sender
+
+	name    string
+	file    storage.Object // nil until the request is received
+	size    int64
+	room    int
+	count   uint32 // packs of file
+	next    uint32 // next pack to write
+	high    uint32 // highest pack received
+	pending map[uint32][]byte
+	hash    hash.Hash
+	sum     []byte // MD5 of file from sender, nil until the finish is received
+	result  []byte // report to sender once the file is done
+	log     logger.Logger
+}
+
+// groupReceiver receive files sent to the multicast group joined by server,
+// see client.Multicast. NACKs and reports are sent by the service.
+type groupReceiver struct {
+	service  *Service
+	conn     *net.UDPConn
+	buf      []byte
+	sessions map[string]*groupSession
+}
+
+// joinGroup join multicast group conf.Group on conf.Interface
+func joinGroup(c *Service) (*groupReceiver, error) {
+	addr, err := net.ResolveUDPAddr("udp", c.conf.Group)
+	if err != nil {
+		return nil, err
+	}
+
+	if !addr.IP.IsMulticast() {
+		return nil, ErrNotMulticast
+	}
+
+	var ifi *net.Interface
+	if c.conf.Interface != "" {
+		if ifi, err = net.InterfaceByName(c.conf.Interface); err != nil {
+			return nil, err
+		}
+	}
+
+	conn, err := net.ListenMulticastUDP("udp", ifi, addr)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetReadBuffer(defaultReadBuffer)
+
+	c.log.Info("join group", "group", addr.String())
+
+	return &groupReceiver{
+		service:  c,
+		conn:     conn,
+		buf:      make([]byte, protocol.MaxPacketSize),
+		sessions: make(map[string]*groupSession),
+	}, nil
+}
+
+// receive read packets of group until conn is closed, and ask senders for
+// lost packs every nackInterval
+func (g *groupReceiver) receive() {
+	tick := time.Now()
+
+	for {
+		g.conn.SetReadDeadline(time.Now().Add(nackInterval))
+		num, addr, err := g.conn.ReadFromUDP(g.buf)
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+
+		if err == nil {
+			g.handle(g.buf[:num], addr)
+		}
+
+		if time.Since(tick) >= nackInterval {
+			tick = time.Now()
+			g.tick()
+		}
+	}
+}
+
+// handle handle a packet from sender addr
+func (g *groupReceiver) handle(p []byte, addr *net.UDPAddr) {
+	if len(p) < protocol.FixedHeaderSize {
+		return
+	}
+
+	proto := protocol.Proto{}
+	pack := protocol.Encode{Body: p, Buffer: bytes.NewBuffer(p)}
+	pack.Unmarshal(&proto)
+
+	switch proto.HeaderType {
+	case protocol.HeaderRequestType, protocol.HeaderFileType, protocol.HeaderFileFinishType:
+	default:
+		return
+	}
+
+	s, ok := g.sessions[addr.String()]
+	if !ok {
+		s = &groupSession{addr: addr}
+		g.sessions[addr.String()] = s
+	}
+	s.active = time.Now()
+
+	if s.result != nil {
+		// sender resends the finish until it gets the report
+		if proto.HeaderType == protocol.HeaderFileFinishType {
+			g.service.Send(s.result, addr)
+		}
+		return
+	}
+
+	if proto.HeaderType == protocol.HeaderRequestType {
+		if s.file == nil {
+			g.start(s, p, &proto)
+		}
+		return
+	}
+
+	if s.file == nil || protocol.FixedHeaderSize+int(proto.PackSize) > len(p) {
+		return
+	}
+	body := p[protocol.FixedHeaderSize : protocol.FixedHeaderSize+int(proto.PackSize)]
+
+	if proto.HeaderType == protocol.HeaderFileType {
+		s.add(proto.PackOrder, body)
+	} else if proto.PackOrder == s.count+1 && len(body) == md5.Size {
+		s.sum = append([]byte(nil), body...)
+		// all packs are sent before the finish
+		s.high = s.count
+	}
+
+	g.flush(s)
+}
+
+// start start receiving file of request p
+func (g *groupReceiver) start(s *groupSession, p []byte, proto *protocol.Proto) {
+	if proto.HeaderSize <= protocol.FixedHeaderSize || int(proto.HeaderSize) > len(p) {
+		return
+	}
+
+	room := int(proto.PackSize) - protocol.FixedHeaderSize
+	if room <= 0 || proto.PackSize > protocol.MaxPacketSize {
+		return
+	}
+
+	opts := protocol.ParseOptions(p[proto.HeaderSize:])
+	size, ok := opts.Uint64(protocol.OptionSize)
+	if !ok || size > uint64(room)*(1<<32-2) {
+		return
+	}
+
+	s.name = string(p[protocol.FileNameOffset:proto.HeaderSize])
+	s.size = int64(size)
+	s.room = room
+	s.count = uint32((s.size + int64(room) - 1) / int64(room))
+	s.next = 1
+	s.pending = make(map[uint32][]byte)
+	s.hash = md5.New()
+
+	s.log = g.service.log.With("remote", s.addr.String(), "file", s.name, "group", true)
+	s.log.Info("receive file", "size", s.size)
+
+	// multicast has no challenge, so it's not for servers require auth
+	if g.service.conf.Auth != nil {
+		g.done(s, protocol.ReplyUnauthorized)
+		return
+	}
+
+	if err := g.service.conf.Policy.Check("", policy.Write, s.name, s.size); err != nil {
+		g.done(s, replyCode(err))
+		return
+	}
+
+	file, err := g.service.pack.storage.Create(s.name)
+	if err != nil {
+		s.log.Error("create file error", "err", err)
+		g.done(s, protocol.ReplyError)
+		return
+	}
+	s.file = file
+}
+
+// add keep pack of order until packs before it are written
+func (s *groupSession) add(order uint32, body []byte) {
+	if order < s.next || order > s.count {
+		return
+	}
+
+	if order > s.high {
+		s.high = order
+	}
+
+	if order >= s.next+maxPending {
+		return
+	}
+
+	size := s.room
+	if order == s.count {
+		size = int(s.size - int64(s.count-1)*int64(s.room))
+	}
+
+	if len(body) != size {
+		return
+	}
+
+	if _, ok := s.pending[order]; !ok {
+		s.pending[order] = append([]byte(nil), body...)
+	}
+}
+
+// flush write packs in order, and finish file once all are written
+func (g *groupReceiver) flush(s *groupSession) {
+	for {
+		body, ok := s.pending[s.next]
+		if !ok {
+			break
+		}
+		delete(s.pending, s.next)
+
+		if _, err := s.file.WriteAt(body, int64(s.next-1)*int64(s.room)); err != nil {
+			s.log.Error("write file error", "err", err)
+			s.file.Abort()
+			g.done(s, protocol.ReplyError)
+			return
+		}
+
+		s.hash.Write(body)
+		s.next++
+	}
+
+	if s.next <= s.count || s.sum == nil {
+		return
+	}
+
+	if !bytes.Equal(s.sum, s.hash.Sum(nil)) {
+		s.log.Error("hash not match")
+		s.file.Abort()
+		g.done(s, protocol.ReplyError)
+		return
+	}
+
+	if err := s.file.Commit(); err != nil {
+		s.log.Error("commit file error", "err", err)
+		g.done(s, protocol.ReplyError)
+		return
+	}
+
+	d := storage.Digest{Size: s.size}
+	copy(d.Sum[:], s.sum)
+	g.service.pack.index.Add(s.name, d)
+
+	s.log.Info("receive file finish")
+	g.done(s, 0)
+}
+
+// done end session with reply, and report it to sender
+func (g *groupReceiver) done(s *groupSession, reply uint32) {
+	s.pending = nil
+	s.result = make([]byte, protocol.FixedHeaderSize)
+
+	report := protocol.Encode{Body: s.result, Buffer: bytes.NewBuffer(s.result)}
+	report.Marshal(&protocol.Proto{
+		HeaderType: protocol.HeaderReportType,
+		HeaderSize: protocol.FixedHeaderSize,
+		PackOrder:  reply,
+	})
+
+	g.service.Send(s.result, s.addr)
+}
+
+// tick ask senders for lost packs, and drop sessions of gone senders
+func (g *groupReceiver) tick() {
+	for key, s := range g.sessions {
+		if time.Since(s.active) > groupTimeout {
+			if s.file != nil && s.result == nil {
+				s.log.Error("sender is gone")
+				s.file.Abort()
+			}
+
+			delete(g.sessions, key)
+			continue
+		}
+
+		if s.result == nil {
+			g.nack(s)
+		}
+	}
+}
+
+// nack ask sender for lost packs of s, the request if it's not received
+func (g *groupReceiver) nack(s *groupSession) {
+	var orders []uint32
+
+	switch {
+	case s.file == nil:
+		orders = append(orders, 0)
+
+	case s.next > s.count && s.sum == nil:
+		orders = append(orders, s.count+1)
+
+	default:
+		for order := s.next; order <= s.high && len(orders) < maxNack; order++ {
+			if _, ok := s.pending[order]; !ok {
+				orders = append(orders, order)
+			}
+		}
+	}
+
+	if len(orders) == 0 {
+		return
+	}
+
+	body := make([]byte, protocol.FixedHeaderSize+4*len(orders))
+	nack := protocol.Encode{Body: body, Buffer: bytes.NewBuffer(body)}
+	nack.Marshal(&protocol.Proto{
+		HeaderType: protocol.HeaderNackType,
+		HeaderSize: protocol.FixedHeaderSize,
+		PackSize:   uint16(4 * len(orders)),
+	})
+	for i, order := range orders {
+		binary.BigEndian.PutUint32(body[protocol.FixedHeaderSize+4*i:], order)
+	}
+
+	g.service.Send(body, s.addr)
+}
@@ -30,25 +30,53 @@
 package server
 
 import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"encoding/hex"
 	"errors"
-	"fmt"
 	"net"
-	"os"
-	"bytes"
+	"time"
 
+	"github.com/TechCatsLab/redalert/codec"
+	"github.com/TechCatsLab/redalert/fec"
+	"github.com/TechCatsLab/redalert/logger"
+	"github.com/TechCatsLab/redalert/metrics"
+	"github.com/TechCatsLab/redalert/policy"
 	"github.com/TechCatsLab/redalert/protocol"
+	"github.com/TechCatsLab/redalert/ratelimit"
+	"github.com/TechCatsLab/redalert/sparse"
+	"github.com/TechCatsLab/redalert/storage"
 	"github.com/TechCatsLab/redalert/udp/remote"
+	"github.com/TechCatsLab/redalert/udp/secure"
 )
 
 // Packet represent a UDP packet
 type Packet struct {
-	proto  *protocol.Proto
-	Body   []byte
-	Size   int
-	Repeat uint8 // flag of packet is if repeat packet
-	Remote *net.UDPAddr
+	proto   *protocol.Proto
+	Body    []byte
+	Size    int
+	Repeat  uint8 // flag of packet is if repeat packet
+	Remote  net.Addr
+	data    []byte // content of file pack, decompressed
+	hole    int64  // length of hole of hole pack
+	storage storage.Storage
+	index   *storage.Index
+
+	sessions *sessionTable
+	checks   *checkTable
+	secure   bool // reject packets not encrypted
+	auth     bool // requests must be authenticated
+	client   string
+	policy   *policy.Policy
+	clients  *ratelimit.Group
+	finished map[string]time.Time // remotes finished lately, their resent finish is done
+	log      logger.Logger
 }
 
+// finishedTTL is how long a finished remote may resend its finish
+const finishedTTL = 10 * time.Second
+
 var (
 	//ErrDuplicated error of duplicated request
 	ErrDuplicated = errors.New("Duplicated request")
@@ -61,40 +89,79 @@ var (
 	// ErrNotExists for client cannot find client address from client table
 	ErrNotExists = errors.New("Remote Address not exists")
 	// ErrHashNotMatch error for hash from client not match with hash which calculated by server
-	ErrHashNotMatch = errors.New("hash value not match")
+	ErrHashNotMatch = remote.ErrHashNotMatch
+	// ErrNotSecure error for packet not encrypted when it should be
+	ErrNotSecure = errors.New("Packet not encrypted")
+	// ErrAuthRequired error for request not authenticated yet
+	ErrAuthRequired = errors.New("Request not authenticated")
+	// ErrInvalidRequest error for header size of request out of range
+	ErrInvalidRequest = errors.New("Invalid request packet")
+	// ErrUnsupported error for option of request not supported
+	ErrUnsupported = errors.New("Option not supported")
+	// ErrPresent error for file of request is present already
+	ErrPresent = errors.New("File is present")
+
+	errGroup = errors.New("Pack of FEC group")
+	// errPending error for packet handled off the receive loop, such as a
+	// request being checked if its file is present or a hole being hashed,
+	// it's dropped until that is done and replied when the client resends it
+	errPending = errors.New("Packet pending")
+	// errNoRemote error for file pack from remote without a transfer, such
+	// as one expired or closed, it's dropped without reply only if remote
+	// is waiting on auth
+	errNoRemote = errors.New("Pack from unknown remote")
 )
 
 // NewPacket generates a Packet with a len([]byte) == cap.
 func NewPacket(cap int) *Packet {
 	return &Packet{
-		Body:   make([]byte, cap),
-		Size:   0,
-		Remote: nil,
-		proto:  &protocol.Proto{},
+		Body:     make([]byte, cap),
+		Size:     0,
+		Remote:   nil,
+		proto:    &protocol.Proto{},
+		finished: make(map[string]time.Time),
 	}
 }
 
-// WriteToUDP write packet to UDP
-func (p *Packet) WriteToUDP(conn *net.UDPConn) error {
-	_, err := conn.WriteToUDP(p.Body[:p.Size], p.Remote)
-
-	fmt.Printf("[WriteToUDP] send %v to %v \n", p.Body, *p.Remote)
+// SendTo write packet to remote of conn
+func (p *Packet) SendTo(conn net.PacketConn) error {
+	_, err := conn.WriteTo(p.Body[:p.Size], p.Remote)
 
 	return err
 }
 
 // Read read packet and handle on the base of type
-func (p *Packet) Read(size int, remote *net.UDPAddr) error {
+func (p *Packet) Read(size int, remote net.Addr) error {
 	var err error
 
 	p.Remote = remote
 	p.Size = size
+	p.client = ""
+
+	if err = p.unseal(); err != nil {
+		return err
+	}
+
+	if p.auth && (p.Body[0] == protocol.HeaderRequestType || p.Body[0] == protocol.HeaderGetType) {
+		return ErrAuthRequired
+	}
+
+	if p.grouped() {
+		return errGroup
+	}
+
+	return p.handle()
+}
+
+// handle handle packet on the base of type
+func (p *Packet) handle() error {
+	var err error
 
 	switch {
 	case p.Body[0] == protocol.HeaderRequestType:
 		err = p.handleRequest()
 
-	case p.Body[0] == protocol.HeaderFileType:
+	case p.Body[0] == protocol.HeaderFileType, p.Body[0] == protocol.HeaderHoleType:
 		err = p.handleFilePacket()
 
 	case p.Body[0] == protocol.HeaderFileFinishType:
@@ -104,6 +171,33 @@ func (p *Packet) Read(size int, remote *net.UDPAddr) error {
 	return err
 }
 
+// unseal decrypt sealed packet in place, packet from remote with an encrypted
+// session must be sealed, except hello which starts a new session.
+func (p *Packet) unseal() error {
+	if p.Body[0] != protocol.HeaderSealedType {
+		if p.Body[0] != protocol.HeaderHelloType && (p.secure || p.sessions.get(p.Remote) != nil) {
+			return ErrNotSecure
+		}
+
+		return nil
+	}
+
+	s := p.sessions.lookup(p.Body[:p.Size], p.Remote)
+	if s == nil {
+		return secure.ErrForged
+	}
+
+	packet, err := s.Open(p.Body[:p.Size])
+	if err != nil {
+		return err
+	}
+	s.timer.Reset(sessionTimeout)
+
+	p.Size = copy(p.Body, packet)
+
+	return nil
+}
+
 // resolve request type pack and add the client who send this pack to online table
 func (p *Packet) handleRequest() error {
 	requestPack := protocol.Encode{
@@ -114,22 +208,119 @@ func (p *Packet) handleRequest() error {
 	// unmarshal to p.proto
 	requestPack.Unmarshal(p.proto)
 
+	if p.proto.HeaderSize < protocol.FixedHeaderSize || int(p.proto.HeaderSize) > p.Size {
+		return ErrInvalidRequest
+	}
+
 	filename := string(p.Body[protocol.FileNameOffset:p.proto.HeaderSize])
 
-	if _, ok := remote.Service.GetRemote(p.Remote); ok {
+	if rem, ok := remote.Service.GetRemote(p.Remote); ok {
+		// repeated request, the reply is lost
+		if rem.FileName == filename && rem.PackCount == 0 {
+			metrics.Duplicates.With(p.Remote.Network()).Inc()
+			p.Repeat = 1
+			return nil
+		}
+
 		return ErrDuplicated
 	}
 
-	file, err := os.Create(protocol.DefaultDir + filename)
+	size := int64(-1)
+	opts := protocol.ParseOptions(p.Body[p.proto.HeaderSize:p.Size])
+	if v, ok := opts.Uint64(protocol.OptionSize); ok {
+		size = int64(v)
+	}
+
+	if err := p.policy.Check(p.client, policy.Write, filename, size); err != nil {
+		metrics.Reject(p.Remote.Network(), metrics.Receive, metrics.ResultDenied)
+		return err
+	}
+
+	if sum, ok := opts[protocol.OptionHash]; ok {
+		client, sum := p.client, append([]byte(nil), sum...)
+		present, done := p.checks.result(p.Remote, filename+"\x00"+string(sum), func() bool {
+			return p.present(client, filename, sum, size)
+		})
+		if !done {
+			return errPending
+		}
+		if present {
+			return ErrPresent
+		}
+	}
+
+	var c codec.Codec
+	if id, ok := opts.Byte(protocol.OptionCodec); ok {
+		if c = codec.Get(id); c == nil {
+			return ErrUnsupported
+		}
+	}
+
+	var group *fec.Decoder
+	if k, ok := opts.Byte(protocol.OptionFEC); ok {
+		if k < fec.MinGroup || k > fec.MaxGroup {
+			return ErrUnsupported
+		}
+		group = fec.NewDecoder(int(k))
+	}
+
+	file, err := p.storage.Create(filename)
 	if err != nil {
 		return err
 	}
 
-	remote.Service.OnStartTransfer(filename, file, p.Remote)
+	rem := remote.Service.OnStartTransfer(filename, file, p.Remote)
+	rem.Limit = p.policy.MaxSize(p.client)
+	rem.Size = size
+	rem.Rate = p.clients.Get(p.clientKey())
+	rem.Codec = c
+	rem.FEC = group
+	rem.Transfer = metrics.Start(p.Remote.Network(), metrics.Receive)
+	rem.Log = p.log.With("remote", p.Remote.String(), "file", filename)
+	if p.client != "" {
+		rem.Log = rem.Log.With("client", p.client)
+	}
+
+	rem.Log.Info("receive file", "codec", c != nil, "fec", group != nil)
 	//p.Body = make([]byte, p.proto.PackSize)
 	return nil
 }
 
+// present tell if name has content of sum and size, or it's copied from
+// another file, client must be permitted to read both files. It's called
+// off the receive loop, so it uses no field of p set by a packet.
+func (p *Packet) present(client, name string, sum []byte, size int64) bool {
+	if len(sum) != md5.Size || size < 0 || p.policy.Check(client, policy.Read, name, -1) != nil {
+		return false
+	}
+
+	d := storage.Digest{Size: size}
+	copy(d.Sum[:], sum)
+
+	return p.index.Ensure(name, d, func(source string) bool {
+		return p.policy.Check(client, policy.Read, source, -1) == nil
+	})
+}
+
+// clientKey return ID of client, or IP of remote if not authenticated
+func (p *Packet) clientKey() string {
+	if p.client != "" {
+		return p.client
+	}
+
+	return sourceIP(p.Remote)
+}
+
+// sourceIP return IP of addr, or addr itself if it has no IP, such as the
+// path of a unixgram socket
+func sourceIP(addr net.Addr) string {
+	if udpAddr, ok := addr.(*net.UDPAddr); ok {
+		return udpAddr.IP.String()
+	}
+
+	return addr.String()
+}
+
 // resolve file type pack and write the content of pack to file
 func (p *Packet) handleFilePacket() error {
 	requestPack := protocol.Encode{
@@ -140,17 +331,27 @@ func (p *Packet) handleFilePacket() error {
 	// unmarshal to p.proto
 	requestPack.Unmarshal(p.proto)
 
-	realBody := p.Body[protocol.FixedHeaderSize : protocol.FixedHeaderSize+p.proto.PackSize]
+	p.data = nil
+	p.hole = 0
 
 	rem, ok := remote.Service.GetRemote(p.Remote)
 	if !ok {
-		return ErrInvalidFilePack
+		return errNoRemote
 	}
 
-	fmt.Printf("[ORDER] is %d \n", p.proto.PackOrder)
+	// grouped packs are dropped before they're added to the group
+	if rem.FEC == nil && rem.Busy() {
+		return errPending
+	}
+
+	if p.Size < protocol.FixedHeaderSize || protocol.FixedHeaderSize+int(p.proto.PackSize) > p.Size {
+		return ErrInvalidFilePack
+	}
+	realBody := p.Body[protocol.FixedHeaderSize : protocol.FixedHeaderSize+p.proto.PackSize]
 
 	if p.proto.PackOrder == rem.PackCount {
-		fmt.Printf("[Repeat packet] %d \n", p.proto.PackOrder)
+		rem.Log.Debug("repeated pack", "order", p.proto.PackOrder)
+		metrics.Duplicates.With(p.Remote.Network()).Inc()
 		p.Repeat = 1
 		return nil
 	}
@@ -161,7 +362,22 @@ func (p *Packet) handleFilePacket() error {
 		return ErrInvalidOrder
 	}
 
-	n, err := rem.File.Write(realBody)
+	if p.proto.HeaderType == protocol.HeaderHoleType {
+		return p.writeHole(rem, realBody)
+	}
+
+	if rem.Codec != nil {
+		var err error
+		if realBody, err = codec.Unpack(rem.Codec, realBody, protocol.MaxPacketSize); err != nil {
+			return err
+		}
+	}
+
+	if rem.Limit > 0 && rem.Offset+int64(len(realBody)) > rem.Limit {
+		return policy.ErrTooLarge
+	}
+
+	n, err := rem.File.WriteAt(realBody, rem.Offset)
 	if err != nil {
 		return err
 	}
@@ -170,6 +386,35 @@ func (p *Packet) handleFilePacket() error {
 		return ErrWrite
 	}
 
+	rem.Offset += int64(n)
+	rem.Transfer.Add(n)
+	p.data = realBody
+
+	return nil
+}
+
+// writeHole skip a hole of file, body is the length of hole, it must end
+// within the size declared by request
+func (p *Packet) writeHole(rem *remote.Remote, body []byte) error {
+	if len(body) != sparse.HoleSize {
+		return ErrInvalidFilePack
+	}
+
+	n := int64(binary.BigEndian.Uint64(body))
+	if n < 0 || rem.Size < 0 || n > rem.Size-rem.Offset {
+		return ErrInvalidFilePack
+	}
+	if rem.Limit > 0 && rem.Offset+n > rem.Limit {
+		return policy.ErrTooLarge
+	}
+
+	if err := storage.Hole(rem.File, rem.Offset, n); err != nil {
+		return err
+	}
+
+	rem.Offset += n
+	p.hole = n
+
 	return nil
 }
 
@@ -185,20 +430,51 @@ func (p *Packet) handleFileFinishPacket() error {
 
 	rem, ok := remote.Service.GetRemote(p.Remote)
 	if !ok {
+		if at, done := p.finished[p.Remote.String()]; done && time.Since(at) < finishedTTL {
+			return nil
+		}
+
 		return ErrNotExists
 	}
 
+	if rem.Busy() {
+		return errPending
+	}
+
+	if p.Size < protocol.FixedHeaderSize+md5.Size {
+		return ErrInvalidFilePack
+	}
+
 	hash := rem.Hash.Sum(nil)
-	fmt.Print("receive finish \n")
-	fmt.Printf("Server hash is %v  \n", hash)
-	if string(p.Body[protocol.FixedHeaderSize:protocol.FixedHeaderSize+16]) != string(hash) {
+	rem.Log.Debug("receive finish", "hash", hex.EncodeToString(hash))
+	if !bytes.Equal(p.Body[protocol.FixedHeaderSize:protocol.FixedHeaderSize+md5.Size], hash) {
+		remote.Service.Close(p.Remote, ErrHashNotMatch)
 		return ErrHashNotMatch
 	}
 
-	remote.Service.Close(p.Remote, nil)
+	if err := remote.Service.Close(p.Remote, nil); err != nil {
+		return err
+	}
+
+	d := storage.Digest{Size: rem.Offset}
+	copy(d.Sum[:], hash)
+	p.index.Add(rem.FileName, d)
+	p.finish(p.Remote.String())
 
 	p.proto.PackOrder = 0
 	p.proto.PackSize = 0
 
 	return nil
 }
+
+// finish record remote finished, and forget remotes finished long ago
+func (p *Packet) finish(remote string) {
+	now := time.Now()
+	for r, at := range p.finished {
+		if now.Sub(at) >= finishedTTL {
+			delete(p.finished, r)
+		}
+	}
+
+	p.finished[remote] = now
+}
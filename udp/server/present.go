@@ -0,0 +1,100 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package server
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// checkTTL is how long the result of a check waits for the client to resend
+// its request
+const checkTTL = 10 * time.Second
+
+// presentCheck is a check if a file of request is present, it runs off the
+// receive loop since a file may be hashed or copied
+type presentCheck struct {
+	key     string // name and MD5 of file
+	at      time.Time
+	done    bool
+	present bool
+}
+
+// checkTable keep checks by remote, a request is dropped until its check is
+// done, then the resent request gets the result
+type checkTable struct {
+	mu     sync.Mutex
+	checks map[string]*presentCheck
+}
+
+func newCheckTable() *checkTable {
+	return &checkTable{
+		checks: make(map[string]*presentCheck),
+	}
+}
+
+// result return result of check key of remote and true if it's done, or
+// start the check by fn if there is none. A result is forgotten once it's
+// returned.
+func (t *checkTable) result(remote net.Addr, key string, fn func() bool) (bool, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	for r, c := range t.checks {
+		if c.done && now.Sub(c.at) >= checkTTL {
+			delete(t.checks, r)
+		}
+	}
+
+	c, ok := t.checks[remote.String()]
+	if ok && c.key == key {
+		if !c.done {
+			return false, false
+		}
+
+		delete(t.checks, remote.String())
+		return c.present, true
+	}
+
+	c = &presentCheck{key: key, at: now}
+	t.checks[remote.String()] = c
+
+	go func() {
+		present := fn()
+
+		t.mu.Lock()
+		c.done, c.present, c.at = true, present, time.Now()
+		t.mu.Unlock()
+	}()
+
+	return false, false
+}
@@ -0,0 +1,302 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package server
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"io"
+	"net"
+	"time"
+
+	"github.com/TechCatsLab/redalert/codec"
+	"github.com/TechCatsLab/redalert/logger"
+	"github.com/TechCatsLab/redalert/metrics"
+	"github.com/TechCatsLab/redalert/policy"
+	"github.com/TechCatsLab/redalert/protocol"
+	"github.com/TechCatsLab/redalert/ratelimit"
+)
+
+const (
+	resendInterval = 500 * time.Millisecond
+	maxResend      = 20
+)
+
+// pull is a download in progress, the server sends packs to remote one by
+// one and remote acks their order with HeaderAckType packs.
+type pull struct {
+	remote   net.Addr
+	file     io.ReadCloser
+	packSize int
+	ack      chan uint32
+	rate     *ratelimit.Bucket
+	codec    codec.Codec
+	log      logger.Logger
+}
+
+// onPull handle download request and ack packs
+func (c *Service) onPull(pack *Packet, size int, remote net.Addr) {
+	if size < protocol.FixedHeaderSize {
+		return
+	}
+
+	proto := protocol.Proto{}
+	decode := protocol.Encode{
+		Body:   pack.Body[:size],
+		Buffer: bytes.NewBuffer(pack.Body[:size]),
+	}
+	decode.Unmarshal(&proto)
+
+	c.pullMu.Lock()
+	p, ok := c.pulls[remote.String()]
+	c.pullMu.Unlock()
+
+	if proto.HeaderType == protocol.HeaderAckType {
+		if ok {
+			select {
+			case p.ack <- proto.PackOrder:
+			default:
+			}
+		}
+
+		return
+	}
+
+	// repeated request, the first pack is on its way
+	if ok {
+		return
+	}
+
+	if proto.HeaderSize < protocol.FixedHeaderSize || int(proto.HeaderSize) > size {
+		return
+	}
+
+	name := string(pack.Body[protocol.FileNameOffset:proto.HeaderSize])
+	reply := make([]byte, protocol.ReplySize)
+
+	log := c.log.With("remote", remote.String(), "file", name)
+	if pack.client != "" {
+		log = log.With("client", pack.client)
+	}
+
+	if c.conf.Export == nil {
+		log.Warn("download not enabled")
+
+		binary.BigEndian.PutUint32(reply, protocol.ReplyError)
+		c.Send(reply, remote)
+		return
+	}
+
+	var cc codec.Codec
+	opts := protocol.ParseOptions(pack.Body[proto.HeaderSize:size])
+	if id, ok := opts.Byte(protocol.OptionCodec); ok {
+		if cc = codec.Get(id); cc == nil {
+			log.Error("codec not supported", "codec", id)
+
+			binary.BigEndian.PutUint32(reply, protocol.ReplyUnsupported)
+			c.Send(reply, remote)
+			return
+		}
+	}
+
+	if err := c.conf.Policy.Check(pack.client, policy.Read, name, -1); err != nil {
+		log.Warn("deny request", "err", err)
+
+		metrics.Reject(remote.Network(), metrics.Send, metrics.ResultDenied)
+
+		binary.BigEndian.PutUint32(reply, protocol.ReplyForbidden)
+		c.Send(reply, remote)
+		return
+	}
+
+	file, err := c.conf.Export.Open(name)
+	if err != nil {
+		log.Error("open file error", "err", err)
+
+		binary.BigEndian.PutUint32(reply, protocol.ReplyNotFound)
+		c.Send(reply, remote)
+		return
+	}
+
+	p = &pull{
+		remote:   remote,
+		file:     file,
+		packSize: int(proto.PackSize),
+		ack:      make(chan uint32, 16),
+		rate:     c.clients.Get(pack.clientKey()),
+		codec:    cc,
+		log:      log,
+	}
+
+	if p.packSize < protocol.FirstPacketSize {
+		p.packSize = protocol.FirstPacketSize
+	}
+
+	if p.packSize > protocol.MaxPacketSize {
+		p.packSize = protocol.MaxPacketSize
+	}
+
+	c.pullMu.Lock()
+	c.pulls[remote.String()] = p
+	c.pullMu.Unlock()
+
+	log.Info("serve file")
+
+	go c.servePull(p)
+}
+
+// servePull send file of p until remote acks the finish pack
+func (c *Service) servePull(p *pull) {
+	network := p.remote.Network()
+	transfer := metrics.Start(network, metrics.Send)
+	result := metrics.ResultError
+
+	defer func() {
+		transfer.Done(result)
+		p.file.Close()
+
+		c.pullMu.Lock()
+		delete(c.pulls, p.remote.String())
+		c.pullMu.Unlock()
+	}()
+
+	pack := protocol.Encode{
+		Body: make([]byte, p.packSize),
+	}
+	pack.Buffer = bytes.NewBuffer(pack.Body)
+
+	hash := md5.New()
+	proto := protocol.Proto{
+		HeaderSize: protocol.FixedHeaderSize,
+	}
+
+	// raw is where file is read, it's compressed into pack.Body
+	raw := pack.Body[protocol.FixedHeaderSize:]
+	if p.codec != nil {
+		raw = make([]byte, p.packSize-protocol.FixedHeaderSize-1)
+	}
+
+	// next read the next pack into pack.Body, return its size
+	next := func() (int, error) {
+		n, err := io.ReadFull(p.file, raw)
+		if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+			return 0, err
+		}
+
+		proto.PackOrder++
+		if err == io.EOF {
+			proto.HeaderType = protocol.HeaderFileFinishType
+			proto.PackSize = md5.Size
+			pack.Marshal(&proto)
+			copy(pack.Body[protocol.FixedHeaderSize:], hash.Sum(nil))
+
+			return protocol.FixedHeaderSize + md5.Size, nil
+		}
+
+		hash.Write(raw[:n])
+		transfer.Add(n)
+
+		if p.codec != nil {
+			packed, err := codec.Pack(p.codec, raw[:n])
+			if err != nil {
+				return 0, err
+			}
+			n = copy(pack.Body[protocol.FixedHeaderSize:], packed)
+		}
+
+		proto.HeaderType = protocol.HeaderFileType
+		proto.PackSize = uint16(n)
+		pack.Marshal(&proto)
+
+		return protocol.FixedHeaderSize + n, nil
+	}
+
+	size, err := next()
+	if err != nil {
+		p.log.Error("read file error", "err", err)
+		return
+	}
+
+	send, resend := true, 0
+	for {
+		if send {
+			time.Sleep(ratelimit.Delay(size, c.limit, p.rate))
+
+			if _, err = c.conn.WriteTo(c.seal(pack.Body[:size], p.remote), p.remote); err != nil {
+				p.log.Error("write pack error", "err", err)
+				return
+			}
+		}
+
+		select {
+		case order := <-p.ack:
+			if proto.HeaderType == protocol.HeaderFileFinishType {
+				if order == protocol.ReplyFinish {
+					p.log.Info("serve file finish")
+
+					result = metrics.ResultOK
+					return
+				}
+
+				if order == protocol.ReplyError {
+					p.log.Error("client reports hash not match")
+
+					result = metrics.ResultHashMismatch
+					return
+				}
+			}
+
+			// ack of a pack has been acked before
+			if order != proto.PackOrder {
+				send = false
+				continue
+			}
+
+			if size, err = next(); err != nil {
+				p.log.Error("read file error", "err", err)
+				return
+			}
+			send, resend = true, 0
+
+		case <-time.After(resendInterval):
+			resend++
+			if resend > maxResend {
+				p.log.Error("serve file time out")
+
+				result = metrics.ResultTimeout
+				return
+			}
+			p.log.Debug("resend pack", "order", proto.PackOrder)
+			metrics.Retransmits.With(network).Inc()
+			send = true
+		}
+	}
+}
@@ -0,0 +1,174 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package server
+
+import (
+	"bytes"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/TechCatsLab/redalert/udp/secure"
+)
+
+const (
+	sessionTimeout = 30 * time.Second
+)
+
+// session is an encrypted session with a remote
+type session struct {
+	*secure.Session
+	remote net.Addr
+	hello  []byte // public key of client, to answer repeated hello
+	reply  []byte
+	timer  *time.Timer
+}
+
+// sessionTable manage encrypted sessions by ID and remote address
+type sessionTable struct {
+	mu     sync.Mutex
+	byID   map[uint64]*session
+	byAddr map[string]*session
+}
+
+func newSessionTable() *sessionTable {
+	return &sessionTable{
+		byID:   make(map[uint64]*session),
+		byAddr: make(map[string]*session),
+	}
+}
+
+// get return session of remote, nil if not exists
+func (t *sessionTable) get(remote net.Addr) *session {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.byAddr[remote.String()]
+}
+
+// lookup return session of a sealed packet from remote
+func (t *sessionTable) lookup(sealed []byte, remote net.Addr) *session {
+	id, ok := secure.ID(sealed)
+	if !ok {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s, ok := t.byID[id]
+	if !ok || s.remote.String() != remote.String() {
+		return nil
+	}
+
+	return s
+}
+
+// add replace the session of s.remote with s, it expires when idle
+func (t *sessionTable) add(s *session) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if old, ok := t.byAddr[s.remote.String()]; ok {
+		old.timer.Stop()
+		delete(t.byID, old.ID)
+	}
+
+	t.byID[s.ID] = s
+	t.byAddr[s.remote.String()] = s
+	s.timer = time.AfterFunc(sessionTimeout, func() {
+		t.remove(s)
+	})
+}
+
+func (t *sessionTable) remove(s *session) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.byID[s.ID] == s {
+		delete(t.byID, s.ID)
+		delete(t.byAddr, s.remote.String())
+	}
+}
+
+// seal seal body if remote has an encrypted session
+func (c *Service) seal(body []byte, remote net.Addr) []byte {
+	if s := c.sessions.get(remote); s != nil {
+		return s.Seal(body)
+	}
+
+	return body
+}
+
+// onHello exchange keys with remote and start an encrypted session
+func (c *Service) onHello(pack *Packet, remote net.Addr) {
+	pub, err := secure.ParseHello(pack.Body[:pack.Size])
+	if err != nil {
+		c.log.Error("hello error", "remote", remote.String(), "err", err)
+		return
+	}
+
+	// repeated hello, the reply is lost
+	if s := c.sessions.get(remote); s != nil && bytes.Equal(s.hello, pub) {
+		c.conn.WriteTo(s.reply, remote)
+		return
+	}
+
+	priv, err := secure.GenerateKey()
+	if err != nil {
+		c.log.Error("generate key error", "err", err)
+		return
+	}
+
+	id, err := secure.NewID()
+	if err != nil {
+		c.log.Error("generate session ID error", "err", err)
+		return
+	}
+
+	sess, err := secure.NewSession(secure.Server, id, priv, pub, c.conf.PSK)
+	if err != nil {
+		c.log.Error("hello error", "remote", remote.String(), "err", err)
+		return
+	}
+
+	s := &session{
+		Session: sess,
+		remote:  remote,
+		hello:   append([]byte(nil), pub...),
+		reply:   secure.HelloReply(priv.PublicKey().Bytes(), id),
+	}
+	c.sessions.add(s)
+
+	c.log.Info("start session", "remote", remote.String(), "session", strconv.FormatUint(id, 16))
+
+	c.conn.WriteTo(s.reply, remote)
+}
@@ -31,94 +31,212 @@ package server
 
 import (
 	"encoding/binary"
-	"fmt"
-	"log"
+	"errors"
 	"net"
+	"os"
+	"sync"
+	"time"
 
+	"github.com/TechCatsLab/redalert/auth"
+	"github.com/TechCatsLab/redalert/logger"
+	"github.com/TechCatsLab/redalert/policy"
 	"github.com/TechCatsLab/redalert/protocol"
+	"github.com/TechCatsLab/redalert/ratelimit"
+	"github.com/TechCatsLab/redalert/storage"
+	"github.com/TechCatsLab/redalert/udp/remote"
+	"github.com/TechCatsLab/redalert/udp/secure"
 )
 
 const (
-	defaultReadBuffer  = 65536
+	defaultReadBuffer  = 1 << 20 // room for FEC groups sent at once
 	defaultWriteBuffer = 65536
+	readErrorDelay     = 100 * time.Millisecond // back off a failing socket
 )
 
 // Conf represents the UDP server configuration, such as IP, port, etc.
 type Conf struct {
-	Address    string // Local Address
-	Port       string // Local Port
-	CacheCount int    // Cache size
+	Address    string          // Local Address
+	Port       string          // Local Port
+	CacheCount int             // Cache size
+	Storage    storage.Storage // Where received files go, default to protocol.DefaultDir
+	Export     storage.Source  // Where downloaded files come from, download is disabled if nil
+	Secure     bool            // Reject packets not encrypted
+	PSK        []byte          // Pre-shared key of encrypted sessions, optional
+	Auth       auth.Keyring    // Clients must authenticate if not nil
+	Policy     *policy.Policy  // What clients may do, everything if nil
+	Group      string          // Multicast group to receive files from, such as 239.0.0.1:17130, not joined if empty
+	Interface  string          // Network interface to join Group on, chosen by system if empty
+	Network    string          // udp or unixgram with Address as path of socket, default to udp
+	Conn       net.PacketConn  // Serve on it instead of listening on Network if not nil
+	Log        logger.Logger   // Where messages go, default to logger.Default()
+
+	Limit       float64 // Bytes per second of all transfers, no limit if 0
+	ClientLimit float64 // Bytes per second of a client, by ID or source IP, no limit if 0
 }
 
 // Service is a UDP service
 type Service struct {
 	conf    *Conf
-	conn    *net.UDPConn
+	conn    net.PacketConn
 	handler Handler
+	pack    *Packet
 	sender  chan *Packet
 	close   chan struct{}
-}
 
-var pack = NewPacket(protocol.FirstPacketSize)
-var reply = make([]byte, protocol.ReplySize)
+	pullMu sync.Mutex
+	pulls  map[string]*pull
 
-// NewServer start a new UDP service
-func NewServer(conf *Conf) *Service {
-	var udpPort string
+	sessions *sessionTable
 
-	if conf.Port == "" {
-		udpPort = ":" + protocol.DefaultUDPPort
-	} else {
-		udpPort = conf.Address + ":" + conf.Port
-	}
+	challengeMu sync.Mutex
+	challenges  map[string]*challenge
 
-	addr, err := net.ResolveUDPAddr("udp", udpPort)
+	limit   *ratelimit.Bucket
+	clients *ratelimit.Group
+
+	group *groupReceiver // receive files of multicast group if not nil
+
+	log logger.Logger
+}
+
+// ErrNetwork network of conf is neither udp nor unixgram
+var ErrNetwork = errors.New("Network not supported")
+
+// NewServer start a new UDP service, the process exits on error
+func NewServer(conf *Conf) *Service {
+	service, err := Listen(conf)
 	if err != nil {
-		log.Fatalln("Can't resolve addr:", err.Error())
+		logger.Or(conf.Log).Error("can't listen", "err", err)
+		os.Exit(1)
 	}
 
-	conn, err := net.ListenUDP("udp", addr)
+	return service
+}
+
+// Listen start a new UDP service listening on conf.Address and conf.Port,
+// or a unixgram service on path conf.Address
+func Listen(conf *Conf) (*Service, error) {
+	conn, err := listen(conf)
 	if err != nil {
-		log.Fatalln("listen error:", err.Error())
+		return nil, err
 	}
 
-	fmt.Printf("[server] start at %v \n", conn.LocalAddr())
+	log := logger.Or(conf.Log)
+	log.Info("server started", "addr", conn.LocalAddr().String())
 
-	hand := Provider{}
+	hand := Provider{log: log}
 	service := &Service{
 		conf:    conf,
 		conn:    conn,
 		handler: &hand,
+		pack:    NewPacket(protocol.MaxPacketSize + secure.Overhead),
 		sender:  make(chan *Packet, 256),
 		close:   make(chan struct{}),
+		pulls:   make(map[string]*pull),
+
+		sessions:   newSessionTable(),
+		challenges: make(map[string]*challenge),
+
+		limit:   ratelimit.NewByteBucket(conf.Limit),
+		clients: ratelimit.NewGroup(conf.ClientLimit),
+
+		log: log,
 	}
+	service.pack.storage = conf.Storage
+	if service.pack.storage == nil {
+		service.pack.storage = storage.NewLocal(protocol.DefaultDir)
+	}
+	service.pack.index = storage.NewIndex(service.pack.storage)
+	service.pack.sessions = service.sessions
+	service.pack.checks = newCheckTable()
+	service.pack.secure = conf.Secure
+	service.pack.auth = conf.Auth != nil
+	service.pack.policy = conf.Policy
+	service.pack.clients = service.clients
+	service.pack.log = log
 	service.prepare()
 
-	go service.HandleClient()
+	if conf.Group != "" {
+		if service.group, err = joinGroup(service); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
 
-	return service
+	return service, nil
+}
+
+// listen open conn of conf.Network, a stale unixgram socket is removed
+func listen(conf *Conf) (net.PacketConn, error) {
+	if conf.Conn != nil {
+		return conf.Conn, nil
+	}
+
+	switch conf.Network {
+	case "", "udp":
+	case "unixgram":
+		if info, err := os.Lstat(conf.Address); err == nil && info.Mode()&os.ModeSocket != 0 {
+			os.Remove(conf.Address)
+		}
+
+		return net.ListenUnixgram("unixgram", &net.UnixAddr{Name: conf.Address, Net: "unixgram"})
+	default:
+		return nil, ErrNetwork
+	}
+
+	var udpPort string
+
+	if conf.Port == "" {
+		udpPort = ":" + protocol.DefaultUDPPort
+	} else {
+		udpPort = net.JoinHostPort(conf.Address, conf.Port)
+	}
+
+	addr, err := net.ResolveUDPAddr("udp", udpPort)
+	if err != nil {
+		return nil, err
+	}
+
+	return net.ListenUDP("udp", addr)
 }
 
 // set buffer size
 func (c *Service) prepare() {
-	c.conn.SetReadBuffer(defaultReadBuffer)
-	c.conn.SetWriteBuffer(defaultWriteBuffer)
+	conn, ok := c.conn.(interface {
+		SetReadBuffer(int) error
+		SetWriteBuffer(int) error
+	})
+	if !ok {
+		return
+	}
+
+	conn.SetReadBuffer(defaultReadBuffer)
+	conn.SetWriteBuffer(defaultWriteBuffer)
 }
 
 // handle event of file transfer
 func (c *Service) HandleClient() {
 	go c.receive()
+	if c.group != nil {
+		go c.group.receive()
+	}
 
 	for {
 		select {
 		case <-c.close:
 			c.conn.Close()
+			if c.group != nil {
+				c.group.conn.Close()
+			}
 
 		case pack := <-c.sender:
-			err := pack.WriteToUDP(c.conn)
+			err := pack.SendTo(c.conn)
+			c.log.Debug("send pack", "remote", pack.Remote.String(), "size", pack.Size)
 
+			// remote may be gone after its finish, such as a closed
+			// unixgram client, its transfer times out if not finished
 			if err != nil {
-				c.handler.OnError(err, nil)
+				c.log.Warn("send error", "remote", pack.Remote.String(), "err", err)
 			}
 		}
 	}
@@ -129,8 +247,9 @@ func (c *Service) Close() {
 	c.close <- struct{}{}
 }
 
-// Send send a packet to remote
-func (c *Service) Send(body []byte, remote *net.UDPAddr) {
+// Send send a packet to remote, it's sealed if remote has an encrypted session
+func (c *Service) Send(body []byte, remote net.Addr) {
+	body = c.seal(body, remote)
 	packet := &Packet{
 		Body:   body,
 		Size:   len(body),
@@ -140,32 +259,157 @@ func (c *Service) Send(body []byte, remote *net.UDPAddr) {
 	c.sender <- packet
 }
 
+// onProbe reply probe with its size, so client knows it gets through
+func (c *Service) onProbe(pack *Packet) {
+	size := make([]byte, protocol.ReplySize)
+	binary.BigEndian.PutUint32(size, uint32(pack.Size))
+	c.Send(size, pack.Remote)
+}
+
+// replyCode return the reply of err
+func replyCode(err error) uint32 {
+	switch {
+	case policy.Denied(err):
+		return protocol.ReplyForbidden
+	case err == ErrUnsupported:
+		return protocol.ReplyUnsupported
+	}
+
+	return protocol.ReplyError
+}
+
+// ack send reply of pack, the reply of a file pack is delayed to limit
+// bandwidth, the client sends the next pack once it gets the reply
+func (c *Service) ack(pack *Packet, reply []byte) {
+	var delay time.Duration
+
+	if pack.proto.HeaderType == protocol.HeaderFileType && pack.Repeat == 0 {
+		if rem, ok := remote.Service.GetRemote(pack.Remote); ok {
+			delay = ratelimit.Delay(int(pack.proto.PackSize), c.limit, rem.Rate)
+		}
+	}
+
+	if delay <= 0 {
+		c.Send(reply, pack.Remote)
+		return
+	}
+
+	body := append([]byte(nil), reply...)
+	addr := pack.Remote
+	time.AfterFunc(delay, func() {
+		c.Send(body, addr)
+	})
+}
+
 // read from udp and handle it
 func (c *Service) receive() {
+	pack := c.pack
+
 	for {
-		size, remote, err := c.conn.ReadFromUDP(pack.Body)
-		fmt.Printf("[Receive] size -----> %d FROM  %v, pack body size %d and %v\n", size, remote, len(pack.Body), pack.Body)
-		if err != nil {
-			c.handler.OnError(err, remote)
+		size, remote, err := c.conn.ReadFrom(pack.Body)
+		if errors.Is(err, net.ErrClosed) {
+			return
 		}
+		if err != nil {
+			c.handler.OnError(err, nil)
 
-		err = pack.Read(size, remote)
-		if pack.proto.HeaderType == protocol.HeaderFileFinishType {
+			time.Sleep(readErrorDelay)
 			continue
 		}
 
-		if err == nil {
-			err = c.handler.OnPacket(pack)
-			if err == nil {
-				binary.BigEndian.PutUint32(reply, pack.proto.PackOrder)
-				c.Send(reply, pack.Remote)
-			}
+		c.log.Debug("receive pack", "remote", remote.String(), "size", size)
+		c.dispatch(pack, pack.Read(size, remote))
+	}
+}
+
+// dispatch handle packet on the base of type and reply remote, err is the
+// result of reading packet. A reply has its own buffer, it's queued to send.
+func (c *Service) dispatch(pack *Packet, err error) {
+	remote := pack.Remote
+	reply := make([]byte, protocol.ReplySize)
+
+	switch err {
+	case ErrNotSecure:
+		if pack.Body[0] == protocol.HeaderRequestType || pack.Body[0] == protocol.HeaderGetType {
+			binary.BigEndian.PutUint32(reply, protocol.ReplyError)
+			c.Send(reply, remote)
+		}
+		fallthrough
+	case secure.ErrForged, secure.ErrReplayed:
+		c.log.Warn("drop packet", "remote", remote.String(), "err", err)
+		return
+
+	// transfer of remote is gone, client stops sending once it's told
+	case errNoRemote:
+		if c.awaitingAuth(remote) {
+			c.log.Warn("drop packet", "remote", remote.String(), "err", err)
+			return
 		}
 
+		binary.BigEndian.PutUint32(reply, protocol.ReplyError)
+		c.Send(reply, remote)
+		return
+
+	case ErrAuthRequired:
+		c.challenge(pack)
+		return
+
+	case ErrPresent:
+		binary.BigEndian.PutUint32(reply, protocol.ReplyPresent)
+		c.Send(reply, remote)
+		return
+
+	case errGroup:
+		c.onGroup(pack)
+		return
+
+	case errPending:
+		return
+	}
+
+	switch pack.Body[0] {
+	case protocol.HeaderHelloType:
+		c.onHello(pack, remote)
+		return
+
+	case protocol.HeaderAuthType:
+		c.onAuth(pack)
+		return
+
+	case protocol.HeaderGetType, protocol.HeaderAckType:
+		c.onPull(pack, pack.Size, remote)
+		return
+
+	case protocol.HeaderProbeType:
+		c.onProbe(pack)
+		return
+	}
+
+	// reply finish even if it's resent, so client knows file is kept
+	if pack.proto.HeaderType == protocol.HeaderFileFinishType {
+		code := uint32(protocol.ReplyFinish)
 		if err != nil {
-			binary.BigEndian.PutUint32(reply, protocol.ReplyError)
-			c.Send(reply, pack.Remote)
-			c.handler.OnError(err, pack.Remote)
+			code = replyCode(err)
+		}
+		binary.BigEndian.PutUint32(reply, code)
+		c.Send(reply, remote)
+		return
+	}
+
+	if err == nil {
+		err = c.handler.OnPacket(pack)
+		if err == errPending {
+			return
 		}
+		if err == nil {
+			binary.BigEndian.PutUint32(reply, pack.proto.PackOrder)
+			c.ack(pack, reply)
+		}
+	}
+
+	if err != nil {
+		binary.BigEndian.PutUint32(reply, replyCode(err))
+		c.Send(reply, pack.Remote)
+		c.handler.OnError(err, pack.Remote)
 	}
 }
@@ -0,0 +1,247 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package ws
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+)
+
+// Opcodes of frames
+const (
+	opContinue = 0x0
+	opText     = 0x1
+	opBinary   = 0x2
+	opClose    = 0x8
+	opPing     = 0x9
+	opPong     = 0xA
+)
+
+const (
+	finBit  = 0x80
+	maskBit = 0x80
+
+	// maxControl is the max payload size of a control frame
+	maxControl = 125
+
+	// closeNormal is the status code of a normal closure
+	closeNormal = 1000
+)
+
+// ErrProtocol error for a frame against RFC 6455
+var ErrProtocol = errors.New("WebSocket protocol error")
+
+// Conn is a WebSocket conn as a byte stream, data is written as binary
+// frames and read from binary or text frames, pings are answered while
+// reading.
+type Conn struct {
+	net.Conn
+	r      *bufio.Reader
+	client bool // mask frames written, as a client must
+
+	remain int64   // bytes of the data frame being read
+	mask   [4]byte // mask of the data frame being read
+	masked bool
+	pos    int // position in mask
+
+	wmu       sync.Mutex
+	closeOnce sync.Once
+}
+
+// newConn make a Conn of conn, r reads conn with what's buffered in
+// handshake
+func newConn(conn net.Conn, r *bufio.Reader, client bool) *Conn {
+	return &Conn{Conn: conn, r: r, client: client}
+}
+
+// Read read payload of data frames
+func (c *Conn) Read(b []byte) (int, error) {
+	for c.remain == 0 {
+		if err := c.next(); err != nil {
+			return 0, err
+		}
+	}
+
+	if int64(len(b)) > c.remain {
+		b = b[:c.remain]
+	}
+
+	n, err := c.r.Read(b)
+	if c.masked {
+		for i := 0; i < n; i++ {
+			b[i] ^= c.mask[c.pos&3]
+			c.pos++
+		}
+	}
+	c.remain -= int64(n)
+
+	return n, err
+}
+
+// next read header of the next data frame, control frames before it are
+// handled, io.EOF once peer closes
+func (c *Conn) next() error {
+	var head [2]byte
+	if _, err := io.ReadFull(c.r, head[:]); err != nil {
+		return err
+	}
+
+	op := head[0] & 0x0F
+	size := int64(head[1] & 0x7F)
+	switch size {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.r, ext[:]); err != nil {
+			return err
+		}
+		size = int64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.r, ext[:]); err != nil {
+			return err
+		}
+		size = int64(binary.BigEndian.Uint64(ext[:]))
+		if size < 0 {
+			return ErrProtocol
+		}
+	}
+
+	c.masked = head[1]&maskBit != 0
+	c.pos = 0
+	if c.masked {
+		if _, err := io.ReadFull(c.r, c.mask[:]); err != nil {
+			return err
+		}
+	}
+
+	switch op {
+	case opContinue, opText, opBinary:
+		c.remain = size
+		return nil
+
+	case opClose, opPing, opPong:
+		if size > maxControl {
+			return ErrProtocol
+		}
+
+		payload := make([]byte, size)
+		if _, err := io.ReadFull(c.r, payload); err != nil {
+			return err
+		}
+		if c.masked {
+			for i := range payload {
+				payload[i] ^= c.mask[i&3]
+			}
+		}
+
+		switch op {
+		case opPing:
+			return c.writeFrame(opPong, payload)
+		case opClose:
+			c.sendClose()
+			return io.EOF
+		}
+
+		return nil
+	}
+
+	return ErrProtocol
+}
+
+// Write write b as a binary frame
+func (c *Conn) Write(b []byte) (int, error) {
+	if err := c.writeFrame(opBinary, b); err != nil {
+		return 0, err
+	}
+
+	return len(b), nil
+}
+
+// writeFrame write a final frame of op, it's masked if c is a client
+func (c *Conn) writeFrame(op byte, payload []byte) error {
+	frame := make([]byte, 0, 14+len(payload))
+	frame = append(frame, finBit|op)
+
+	var flag byte
+	if c.client {
+		flag = maskBit
+	}
+
+	switch size := len(payload); {
+	case size < 126:
+		frame = append(frame, flag|byte(size))
+	case size <= 0xFFFF:
+		frame = append(frame, flag|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(size))
+	default:
+		frame = append(frame, flag|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(size))
+	}
+
+	if !c.client {
+		frame = append(frame, payload...)
+	} else {
+		var mask [4]byte
+		if _, err := rand.Read(mask[:]); err != nil {
+			return err
+		}
+		frame = append(frame, mask[:]...)
+		for i, v := range payload {
+			frame = append(frame, v^mask[i&3])
+		}
+	}
+
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	_, err := c.Conn.Write(frame)
+
+	return err
+}
+
+// sendClose send a close frame once
+func (c *Conn) sendClose() {
+	c.closeOnce.Do(func() {
+		var status [2]byte
+		binary.BigEndian.PutUint16(status[:], closeNormal)
+		c.writeFrame(opClose, status[:])
+	})
+}
+
+// Close send a close frame and close the underlying conn
+func (c *Conn) Close() error {
+	c.sendClose()
+	return c.Conn.Close()
+}
@@ -0,0 +1,249 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Co.,Ltd..
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2026/10/19
+ */
+
+package ws
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// magic is appended to the key of client to compute the accept of server
+const magic = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+var (
+	// ErrHandshake error for a handshake against RFC 6455
+	ErrHandshake = errors.New("WebSocket handshake failed")
+
+	// ErrProxy error for a proxy refused to connect
+	ErrProxy = errors.New("Proxy refused to connect")
+)
+
+// accept return Sec-WebSocket-Accept of key
+func accept(key string) string {
+	sum := sha1.Sum([]byte(key + magic))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// SplitAddr split addr as host:port/path, path is "/" if omitted
+func SplitAddr(addr string) (string, string) {
+	if i := strings.Index(addr, "/"); i >= 0 {
+		return addr[:i], addr[i:]
+	}
+
+	return addr, "/"
+}
+
+// Dial connect addr as host:port/path, through the proxy of environment
+// such as HTTPS_PROXY if any. It's wss if tlsConf is not nil.
+func Dial(addr string, tlsConf *tls.Config) (*Conn, error) {
+	hostport, _ := SplitAddr(addr)
+
+	scheme := "http"
+	if tlsConf != nil {
+		scheme = "https"
+	}
+
+	proxy, err := http.ProxyFromEnvironment(&http.Request{URL: &url.URL{Scheme: scheme, Host: hostport}})
+	if err != nil {
+		return nil, err
+	}
+
+	var conn net.Conn
+	if proxy != nil {
+		conn, err = dialProxy(proxy, hostport)
+	} else {
+		conn, err = net.Dial("tcp", hostport)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if tlsConf != nil {
+		if tlsConf.ServerName == "" {
+			tlsConf = tlsConf.Clone()
+			tlsConf.ServerName, _, _ = net.SplitHostPort(hostport)
+		}
+
+		tlsConn := tls.Client(conn, tlsConf)
+		if err = tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+
+	c, err := Client(conn, addr)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// dialProxy connect hostport by CONNECT of an HTTP proxy
+func dialProxy(proxy *url.URL, hostport string) (net.Conn, error) {
+	proxyAddr := proxy.Host
+	if proxy.Port() == "" {
+		proxyAddr = net.JoinHostPort(proxy.Hostname(), "80")
+	}
+
+	conn, err := net.Dial("tcp", proxyAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	req := fmt.Sprintf("CONNECT %s HTTP/1.1\r\nHost: %s\r\n", hostport, hostport)
+	if user := proxy.User; user != nil {
+		password, _ := user.Password()
+		auth := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+		req += "Proxy-Authorization: Basic " + auth + "\r\n"
+	}
+
+	if _, err = conn.Write([]byte(req + "\r\n")); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	// the proxy says nothing more before the tunnel is used
+	resp, err := http.ReadResponse(bufio.NewReaderSize(conn, 1), nil)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, ErrProxy
+	}
+
+	return conn, nil
+}
+
+// Client handshake as a client on conn to addr as host:port/path
+func Client(conn net.Conn, addr string) (*Conn, error) {
+	hostport, path := SplitAddr(addr)
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce)
+
+	req := fmt.Sprintf("GET %s HTTP/1.1\r\n"+
+		"Host: %s\r\n"+
+		"Upgrade: websocket\r\n"+
+		"Connection: Upgrade\r\n"+
+		"Sec-WebSocket-Key: %s\r\n"+
+		"Sec-WebSocket-Version: 13\r\n\r\n", path, hostport, key)
+	if _, err := conn.Write([]byte(req)); err != nil {
+		return nil, err
+	}
+
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: %s", ErrHandshake, resp.Status)
+	}
+
+	if resp.Header.Get("Sec-WebSocket-Accept") != accept(key) {
+		return nil, ErrHandshake
+	}
+
+	return newConn(conn, r, true), nil
+}
+
+// Upgrade handshake as a server with the request of client, an error is
+// replied if it's not a WebSocket handshake
+func Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if r.Method != http.MethodGet || key == "" ||
+		!hasToken(r.Header, "Connection", "upgrade") ||
+		!hasToken(r.Header, "Upgrade", "websocket") ||
+		r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "Not a WebSocket handshake", http.StatusBadRequest)
+		return nil, ErrHandshake
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "WebSocket not supported", http.StatusInternalServerError)
+		return nil, ErrHandshake
+	}
+
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	// deadlines of the http server don't apply to the conn any more
+	conn.SetDeadline(time.Time{})
+
+	resp := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + accept(key) + "\r\n\r\n"
+	if _, err = conn.Write([]byte(resp)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return newConn(conn, rw.Reader, false), nil
+}
+
+// hasToken tell if header key has token in its comma separated list
+func hasToken(header http.Header, key, token string) bool {
+	for _, value := range header.Values(key) {
+		for _, t := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+
+	return false
+}